@@ -0,0 +1,88 @@
+package configmaster
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"unicode/utf8"
+)
+
+// checkConstraints checks a value against the min, max, multipleOf, minLength, maxLength and pattern properties of a schema leaf.
+// Numeric constraints only apply to numbers and string constraints only to strings, the format decides the type.
+// It returns the name of the first constraint that fails together with the error describing it.
+func checkConstraints(value interface{}, config map[string]interface{}) (string, error) {
+	// Check the numeric constraints.
+	if number, ok := numericValue(value); ok {
+		for _, constraint := range []string{"min", "max", "multipleOf"} {
+			rawLimit, exists := config[constraint]
+			if !exists {
+				continue
+			}
+			limit, ok := numericValue(rawLimit)
+			if !ok {
+				return constraint, fmt.Errorf("invalid %s constraint %#v, expected a number", constraint, rawLimit)
+			}
+			switch {
+			case constraint == "min" && number < limit:
+				return constraint, fmt.Errorf("value %v is less than the minimum %v", value, rawLimit)
+			case constraint == "max" && number > limit:
+				return constraint, fmt.Errorf("value %v is greater than the maximum %v", value, rawLimit)
+			case constraint == "multipleOf" && limit <= 0:
+				return constraint, fmt.Errorf("invalid multipleOf constraint %v, expected a positive number", rawLimit)
+			case constraint == "multipleOf" && !isMultipleOf(number, limit):
+				return constraint, fmt.Errorf("value %v is not a multiple of %v", value, rawLimit)
+			}
+		}
+	}
+
+	// Check the string constraints.
+	text, ok := value.(string)
+	if !ok {
+		return "", nil
+	}
+	length := utf8.RuneCountInString(text)
+	for _, constraint := range []string{"minLength", "maxLength"} {
+		rawLimit, exists := config[constraint]
+		if !exists {
+			continue
+		}
+		limit, ok := toInt(rawLimit)
+		if _, isString := rawLimit.(string); !ok || isString || limit < 0 {
+			return constraint, fmt.Errorf("invalid %s constraint %#v, expected a non-negative integer", constraint, rawLimit)
+		}
+		switch {
+		case constraint == "minLength" && length < limit:
+			return constraint, fmt.Errorf("length %d of value %q is less than the minimum length %d", length, text, limit)
+		case constraint == "maxLength" && length > limit:
+			return constraint, fmt.Errorf("length %d of value %q is greater than the maximum length %d", length, text, limit)
+		}
+	}
+	if rawPattern, exists := config["pattern"]; exists {
+		pattern, ok := rawPattern.(string)
+		if !ok {
+			return "pattern", fmt.Errorf("invalid pattern constraint %#v, expected a string", rawPattern)
+		}
+		expression, err := regexp.Compile(pattern)
+		if err != nil {
+			return "pattern", fmt.Errorf("invalid pattern constraint %q: %w", pattern, err)
+		}
+		if !expression.MatchString(text) {
+			return "pattern", fmt.Errorf("value %q does not match the pattern %q", text, pattern)
+		}
+	}
+	return "", nil
+}
+
+// numericValue converts numbers of any type into a float64, strings are not converted.
+func numericValue(value interface{}) (float64, bool) {
+	if _, isString := value.(string); isString {
+		return 0, false
+	}
+	return toFloat64(value)
+}
+
+// isMultipleOf reports whether a number is a whole multiple of a positive divisor, allowing for floating point rounding.
+func isMultipleOf(number float64, divisor float64) bool {
+	quotient := number / divisor
+	return math.Abs(quotient-math.Round(quotient)) <= 1e-9*math.Max(1, math.Abs(quotient))
+}
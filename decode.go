@@ -0,0 +1,258 @@
+package configmaster
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// durationType is the reflected type of time.Duration, which is decoded from strings instead of integers.
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// DecodeError is returned by Decode and DecodeKey when a value cannot be stored in the target.
+type DecodeError struct {
+	// Path is the full path of the value that failed to convert, e.g. "db.replicas[1].port".
+	Path string
+	// Err describes why the conversion failed.
+	Err error
+}
+
+// Error returns a description of the failed conversion naming the path of the value.
+func (e *DecodeError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("decode: %v", e.Err)
+	}
+	return fmt.Sprintf("decode %q: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Decode stores the resolved configuration data in the struct, map or other value pointed to by target.
+// Struct fields are matched by their `config:"name"` tag, or by their name ignoring case when untagged,
+// fields tagged `config:"-"` are skipped and embedded structs without a tag share the keys of their parent.
+func (c *Config) Decode(target interface{}) error {
+	return decodeTarget("", c.current().data, target)
+}
+
+// DecodeKey stores the value found at the dotted key in the struct, map or other value pointed to by target.
+func (c *Config) DecodeKey(key string, target interface{}) error {
+	value, err := c.lookupValue(key)
+	if err != nil {
+		return err
+	}
+	return decodeTarget(key, value, target)
+}
+
+// decodeTarget checks that the target is a non-nil pointer and decodes the value into the value it points to.
+func decodeTarget(path string, value interface{}, target interface{}) error {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr || targetValue.IsNil() {
+		return &DecodeError{Path: path, Err: fmt.Errorf("target must be a non-nil pointer, got %T", target)}
+	}
+	return decodeValue(path, value, targetValue.Elem())
+}
+
+// decodeValue converts a value from the configuration data and stores it in the target.
+func decodeValue(path string, value interface{}, target reflect.Value) error {
+	// Missing values leave the target untouched.
+	if value == nil {
+		return nil
+	}
+
+	// Durations are converted from strings and numbers.
+	if target.Type() == durationType {
+		duration, ok := toDuration(value)
+		if !ok {
+			return newDecodeTypeError(path, value, target.Type())
+		}
+		target.SetInt(int64(duration))
+		return nil
+	}
+
+	// Values that already have the target type, such as formatted values, are stored as they are.
+	// Maps and slices of the configuration data are decoded item by item unless the target is an interface, which gets a copy.
+	valueType := reflect.TypeOf(value)
+	_, isMap := value.(map[string]interface{})
+	_, isSlice := value.([]interface{})
+	if valueType.AssignableTo(target.Type()) && (target.Kind() == reflect.Interface || (!isMap && !isSlice)) {
+		target.Set(reflect.ValueOf(copyValue(value)))
+		return nil
+	}
+
+	// Convert the value according to the kind of the target.
+	switch target.Kind() {
+	case reflect.Ptr:
+		// Allocate the pointer if needed and decode into the value it points to.
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		return decodeValue(path, value, target.Elem())
+	case reflect.Struct:
+		mapValue, ok := value.(map[string]interface{})
+		if !ok {
+			return newDecodeTypeError(path, value, target.Type())
+		}
+		return decodeStruct(path, mapValue, target)
+	case reflect.Map:
+		mapValue, ok := value.(map[string]interface{})
+		if !ok || target.Type().Key().Kind() != reflect.String {
+			return newDecodeTypeError(path, value, target.Type())
+		}
+		return decodeMap(path, mapValue, target)
+	case reflect.Slice:
+		sliceValue, ok := value.([]interface{})
+		if !ok {
+			return newDecodeTypeError(path, value, target.Type())
+		}
+		// Decode every item into a new slice of the target type.
+		decodedSlice := reflect.MakeSlice(target.Type(), len(sliceValue), len(sliceValue))
+		for index, item := range sliceValue {
+			if err := decodeValue(fmt.Sprintf("%s[%d]", path, index), item, decodedSlice.Index(index)); err != nil {
+				return err
+			}
+		}
+		target.Set(decodedSlice)
+		return nil
+	case reflect.Array:
+		sliceValue, ok := value.([]interface{})
+		if !ok || len(sliceValue) != target.Len() {
+			return newDecodeTypeError(path, value, target.Type())
+		}
+		for index, item := range sliceValue {
+			if err := decodeValue(fmt.Sprintf("%s[%d]", path, index), item, target.Index(index)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.String:
+		result, ok := toString(value)
+		if !ok {
+			return newDecodeTypeError(path, value, target.Type())
+		}
+		target.SetString(result)
+		return nil
+	case reflect.Bool:
+		result, ok := toBool(value)
+		if !ok {
+			return newDecodeTypeError(path, value, target.Type())
+		}
+		target.SetBool(result)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		result, ok := toInt(value)
+		if !ok || target.OverflowInt(int64(result)) {
+			return newDecodeTypeError(path, value, target.Type())
+		}
+		target.SetInt(int64(result))
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		result, ok := toInt(value)
+		if !ok || result < 0 || target.OverflowUint(uint64(result)) {
+			return newDecodeTypeError(path, value, target.Type())
+		}
+		target.SetUint(uint64(result))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		result, ok := toFloat64(value)
+		if !ok || target.OverflowFloat(result) {
+			return newDecodeTypeError(path, value, target.Type())
+		}
+		target.SetFloat(result)
+		return nil
+	case reflect.Interface:
+		// Interfaces that the value implements were handled above.
+		return newDecodeTypeError(path, value, target.Type())
+	default:
+		return &DecodeError{Path: path, Err: fmt.Errorf("unsupported target type %s", target.Type())}
+	}
+}
+
+// decodeStruct stores the values of a map in the matching fields of a struct.
+func decodeStruct(path string, value map[string]interface{}, target reflect.Value) error {
+	targetType := target.Type()
+	for index := 0; index < targetType.NumField(); index++ {
+		field := targetType.Field(index)
+
+		// Skip unexported fields and fields tagged with "-".
+		name, _, _ := strings.Cut(field.Tag.Get("config"), ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		// Embedded structs without a name share the keys of their parent.
+		if field.Anonymous && name == "" {
+			embedded := target.Field(index)
+			if embedded.Kind() == reflect.Ptr && embedded.Type().Elem().Kind() == reflect.Struct {
+				if !field.IsExported() {
+					// Unexported embedded pointers cannot be allocated.
+					continue
+				}
+				if embedded.IsNil() {
+					embedded.Set(reflect.New(embedded.Type().Elem()))
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := decodeStruct(path, value, embedded); err != nil {
+					return err
+				}
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+		}
+
+		// Find the value by the tag name, or by the field name ignoring case.
+		if name == "" {
+			name = field.Name
+		}
+		fieldValue, key, found := lookupField(value, name)
+		if !found {
+			continue
+		}
+		if err := decodeValue(joinPath(path, key), fieldValue, target.Field(index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeMap stores the values of a map in a map with string keys.
+func decodeMap(path string, value map[string]interface{}, target reflect.Value) error {
+	targetType := target.Type()
+	if target.IsNil() {
+		target.Set(reflect.MakeMapWithSize(targetType, len(value)))
+	}
+	for key, item := range value {
+		// Decode every item into a new value of the element type.
+		element := reflect.New(targetType.Elem()).Elem()
+		if err := decodeValue(joinPath(path, key), item, element); err != nil {
+			return err
+		}
+		target.SetMapIndex(reflect.ValueOf(key).Convert(targetType.Key()), element)
+	}
+	return nil
+}
+
+// lookupField finds the value for a struct field, preferring an exact key match over a case-insensitive one.
+func lookupField(value map[string]interface{}, name string) (interface{}, string, bool) {
+	if fieldValue, exists := value[name]; exists {
+		return fieldValue, name, true
+	}
+	for key, fieldValue := range value {
+		if strings.EqualFold(key, name) {
+			return fieldValue, key, true
+		}
+	}
+	return nil, "", false
+}
+
+// newDecodeTypeError returns a DecodeError for a value that cannot be converted to the target type.
+func newDecodeTypeError(path string, value interface{}, targetType reflect.Type) error {
+	return &DecodeError{Path: path, Err: fmt.Errorf("cannot convert %T value %v to %s", value, value, targetType)}
+}
@@ -0,0 +1,172 @@
+package configmaster
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvPrecedence decides which environment wins when a variable is set both in a .env file and in the environment source.
+type EnvPrecedence int
+
+const (
+	// ProcessEnvFirst lets the environment source, the process environment by default, override the values of .env files.
+	ProcessEnvFirst EnvPrecedence = iota
+	// DotEnvFirst lets the values of .env files override the environment source.
+	DotEnvFirst
+)
+
+// layerDotEnv combines an environment source with the variables of .env files according to the precedence.
+func layerDotEnv(source EnvSource, dotEnv map[string]string, precedence EnvPrecedence) EnvSource {
+	// Without .env variables the source is used as is.
+	if len(dotEnv) == 0 {
+		return source
+	}
+	if precedence == DotEnvFirst {
+		return ChainEnv{MapEnv(dotEnv), source}
+	}
+	return ChainEnv{source, MapEnv(dotEnv)}
+}
+
+// loadDotEnvFiles reads the .env files in order, later files overriding the variables of earlier ones.
+func loadDotEnvFiles(filenames []string) (map[string]string, error) {
+	variables := make(map[string]string)
+	for _, filename := range filenames {
+		// Read the file contents.
+		byteValue, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("error reading .env file: %w", err)
+		}
+
+		// Parse the variables and merge them over the ones already loaded.
+		fileVariables, err := parseDotEnv(byteValue)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing .env file %s: %w", filename, err)
+		}
+		for key, value := range fileVariables {
+			variables[key] = value
+		}
+	}
+	return variables, nil
+}
+
+// parseDotEnv parses the KEY=VALUE lines of a .env file.
+// Values may be double-quoted with escapes, single-quoted literals or unquoted with trailing comments,
+// quoted values may span multiple lines and keys may carry an `export` prefix.
+func parseDotEnv(byteValue []byte) (map[string]string, error) {
+	variables := make(map[string]string)
+
+	// Normalise line endings so that only \n has to be handled.
+	data := strings.ReplaceAll(string(byteValue), "\r\n", "\n")
+	line := 1
+
+	for len(data) > 0 {
+		// Take the next line, keeping the remaining data for multi-line values.
+		current, rest, hasNewline := strings.Cut(data, "\n")
+		startLine := line
+		data = rest
+		line++
+
+		// Skip blank lines and comments.
+		current = strings.TrimLeft(current, " \t")
+		if strings.TrimSpace(current) == "" || strings.HasPrefix(current, "#") {
+			continue
+		}
+
+		// Strip the optional export prefix.
+		if withoutExport, found := strings.CutPrefix(current, "export "); found {
+			current = strings.TrimLeft(withoutExport, " \t")
+		}
+
+		// Split the key from the value.
+		key, value, found := strings.Cut(current, "=")
+		key = strings.TrimSpace(key)
+		if !found || !isDotEnvKey(key) {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE, got %q", startLine, strings.TrimSpace(current))
+		}
+		value = strings.TrimLeft(value, " \t")
+
+		// Parse the value according to its quoting.
+		if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") {
+			// Quoted values may continue on the following lines, so put the line back together with the rest of the data.
+			quoted := value[1:]
+			if hasNewline {
+				quoted += "\n" + data
+			}
+			parsed, remaining, err := parseDotEnvQuoted(quoted, value[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", startLine, err)
+			}
+
+			// Only a comment may follow the closing quote on its line.
+			trailing, remainingData, _ := strings.Cut(remaining, "\n")
+			trailing = strings.TrimSpace(trailing)
+			if trailing != "" && !strings.HasPrefix(trailing, "#") {
+				return nil, fmt.Errorf("line %d: unexpected %q after quoted value", startLine, trailing)
+			}
+			line += strings.Count(quoted, "\n") - strings.Count(remaining, "\n")
+			data = remainingData
+			variables[key] = parsed
+			continue
+		}
+
+		// Unquoted values end at an inline comment, which starts with a # after whitespace.
+		for index := 0; index < len(value); index++ {
+			if value[index] == '#' && (index == 0 || value[index-1] == ' ' || value[index-1] == '\t') {
+				value = value[:index]
+				break
+			}
+		}
+		variables[key] = strings.TrimSpace(value)
+	}
+	return variables, nil
+}
+
+// parseDotEnvQuoted parses a quoted value up to its closing quote and returns the value and the data following it.
+// Double-quoted values support the \n, \r, \t, \" and \\ escapes, single-quoted values are taken literally.
+func parseDotEnvQuoted(data string, quote byte) (string, string, error) {
+	var builder strings.Builder
+	for index := 0; index < len(data); index++ {
+		char := data[index]
+		switch {
+		case char == quote:
+			return builder.String(), data[index+1:], nil
+		case char == '\\' && quote == '"' && index+1 < len(data):
+			// Replace the escape sequence with the character it stands for.
+			index++
+			switch data[index] {
+			case 'n':
+				builder.WriteByte('\n')
+			case 'r':
+				builder.WriteByte('\r')
+			case 't':
+				builder.WriteByte('\t')
+			case '"', '\\':
+				builder.WriteByte(data[index])
+			default:
+				// Unknown escapes are kept as they are.
+				builder.WriteByte('\\')
+				builder.WriteByte(data[index])
+			}
+		default:
+			builder.WriteByte(char)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated %c quoted value", quote)
+}
+
+// isDotEnvKey reports whether a key is a valid environment variable name.
+func isDotEnvKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for index, char := range key {
+		switch {
+		case char == '_' || (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z'):
+		case index > 0 && ((char >= '0' && char <= '9') || char == '.' || char == '-'):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package configmaster
+
+import "os"
+
+// EnvSource looks up the variables referenced by "env" properties.
+type EnvSource interface {
+	// LookupEnv retrieves the value of the variable named by the key and reports whether it is set.
+	LookupEnv(key string) (string, bool)
+}
+
+// OSEnv looks up variables in the process environment.
+type OSEnv struct{}
+
+// LookupEnv retrieves the value of a variable from the process environment.
+func (OSEnv) LookupEnv(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+// MapEnv looks up variables in a map, which makes it possible to resolve a configuration without touching the process environment.
+type MapEnv map[string]string
+
+// LookupEnv retrieves the value of a variable from the map.
+func (m MapEnv) LookupEnv(key string) (string, bool) {
+	value, exists := m[key]
+	return value, exists
+}
+
+// ChainEnv looks up variables in each source in order and returns the first one that is set.
+type ChainEnv []EnvSource
+
+// LookupEnv retrieves the value of a variable from the first source that has it.
+func (c ChainEnv) LookupEnv(key string) (string, bool) {
+	for _, source := range c {
+		if value, exists := source.LookupEnv(key); exists {
+			return value, true
+		}
+	}
+	return "", false
+}
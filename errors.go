@@ -0,0 +1,63 @@
+package configmaster
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrRequired is wrapped by the validation errors of required keys that have no value.
+var ErrRequired = errors.New("required value is missing")
+
+// ValidationError describes a single configuration key that failed validation.
+type ValidationError struct {
+	// Key is the full dotted path of the key, e.g. "qux.bar" or "xyz[1].bar".
+	Key string
+	// Value is the offending value.
+	Value interface{}
+	// Source describes where the value came from, e.g. the default or the QUX_BAR environment variable.
+	Source Source
+	// Rule names the validation rule that failed, e.g. "format" or "required".
+	Rule string
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns a human readable description of the validation error.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %v (rule %q, value %#v from %s)", e.Key, e.Err, e.Rule, e.Value, e.Source)
+}
+
+// Unwrap returns the underlying error.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors is the report of every configuration key that failed validation.
+type ValidationErrors []*ValidationError
+
+// Error returns a human readable report listing every validation error.
+func (e ValidationErrors) Error() string {
+	// A single error is reported on its own.
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	// Multiple errors are reported one per line.
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%d validation errors:", len(e))
+	for _, validationError := range e {
+		builder.WriteString("\n\t")
+		builder.WriteString(validationError.Error())
+	}
+	return builder.String()
+}
+
+// Unwrap returns the individual validation errors so that errors.Is and errors.As can inspect them.
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for index, validationError := range e {
+		errs[index] = validationError
+	}
+	return errs
+}
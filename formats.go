@@ -0,0 +1,257 @@
+package configmaster
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parsePort checks that a value is a port number and returns it as an int.
+func parsePort(value interface{}) (interface{}, error) {
+	port, ok := value.(int)
+	if !ok || port < 0 || port > 65535 {
+		return nil, errors.New("value is not a port number between 0 and 65535")
+	}
+	return port, nil
+}
+
+// parseNat checks that a value is a natural number, zero included, and returns it as an int.
+func parseNat(value interface{}) (interface{}, error) {
+	number, ok := value.(int)
+	if !ok || number < 0 {
+		return nil, errors.New("value is not a natural number")
+	}
+	return number, nil
+}
+
+// parseURL parses an absolute URL into a *url.URL.
+func parseURL(value interface{}) (interface{}, error) {
+	// URLs that were already parsed are kept as they are.
+	if parsedURL, ok := value.(*url.URL); ok {
+		return parsedURL, nil
+	}
+	text, ok := value.(string)
+	if !ok {
+		return nil, errors.New("value is not a URL string")
+	}
+	parsedURL, err := url.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("value is not a valid URL: %w", err)
+	}
+	if parsedURL.Scheme == "" || parsedURL.Host == "" {
+		return nil, errors.New("value is not an absolute URL with a scheme and a host")
+	}
+	return parsedURL, nil
+}
+
+// parseEmail checks that a value is a plain email address, without a display name, and returns it as a string.
+func parseEmail(value interface{}) (interface{}, error) {
+	text, ok := value.(string)
+	if !ok {
+		return nil, errors.New("value is not an email address string")
+	}
+	address, err := mail.ParseAddress(text)
+	if err != nil || address.Name != "" || address.Address != text {
+		return nil, errors.New("value is not a valid email address")
+	}
+	return text, nil
+}
+
+// parseIPAddress parses an IP address into a net.IP, restricted to IPv4 or IPv6 addresses when the version is 4 or 6.
+func parseIPAddress(value interface{}, version int) (interface{}, error) {
+	// Addresses that were already parsed are checked again for their version.
+	ip, ok := value.(net.IP)
+	if !ok {
+		text, isString := value.(string)
+		if !isString {
+			return nil, errors.New("value is not an IP address string")
+		}
+		ip = net.ParseIP(text)
+		if ip == nil {
+			return nil, errors.New("value is not a valid IP address")
+		}
+		// IPv6 addresses are only told apart from IPv4 ones by their text.
+		if version == 6 && !strings.Contains(text, ":") {
+			return nil, errors.New("value is not an IPv6 address")
+		}
+	}
+	switch {
+	case version == 4 && ip.To4() == nil:
+		return nil, errors.New("value is not an IPv4 address")
+	case version == 6 && ip.To16() == nil:
+		return nil, errors.New("value is not an IPv6 address")
+	}
+	return ip, nil
+}
+
+// parseHostname checks that a value is a hostname made of RFC 1123 labels and returns it as a string.
+func parseHostname(value interface{}) (interface{}, error) {
+	text, ok := value.(string)
+	if !ok {
+		return nil, errors.New("value is not a hostname string")
+	}
+
+	// A single trailing dot marks a fully qualified name.
+	name := strings.TrimSuffix(text, ".")
+	if name == "" || len(name) > 253 {
+		return nil, errors.New("value is not a valid hostname")
+	}
+
+	// Every label is made of letters, digits and inner hyphens.
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return nil, fmt.Errorf("value is not a valid hostname: invalid label %q", label)
+		}
+		for _, char := range label {
+			if char != '-' && !isASCIILetterOrDigit(char) {
+				return nil, fmt.Errorf("value is not a valid hostname: invalid label %q", label)
+			}
+		}
+	}
+	return text, nil
+}
+
+// parseDurationValue parses a Go duration string, or a whole number of nanoseconds, into a time.Duration.
+func parseDurationValue(value interface{}) (interface{}, error) {
+	// Text that is not a duration would be converted by toDuration when it holds a number, so strings are parsed here.
+	if text, ok := value.(string); ok {
+		duration, err := time.ParseDuration(strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("value is not a valid duration: %w", err)
+		}
+		return duration, nil
+	}
+	duration, ok := toDuration(value)
+	if !ok {
+		return nil, errors.New("value is not a duration")
+	}
+	return duration, nil
+}
+
+// parseTimestamp parses an RFC 3339 timestamp into a time.Time.
+func parseTimestamp(value interface{}) (interface{}, error) {
+	// YAML and TOML decode timestamps themselves.
+	if timestamp, ok := value.(time.Time); ok {
+		return timestamp, nil
+	}
+	text, ok := value.(string)
+	if !ok {
+		return nil, errors.New("value is not a timestamp string")
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an RFC 3339 timestamp: %w", err)
+	}
+	return timestamp, nil
+}
+
+// cronFields describes the range and the names of every field of a cron expression.
+var cronFields = []struct {
+	name     string
+	min, max int
+	names    []string
+}{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}},
+}
+
+// cronMacros lists the shorthand schedules accepted in place of the five fields.
+var cronMacros = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+
+// parseCron checks that a value is a cron expression with five fields, a macro such as "@daily" or "@every <duration>",
+// and returns it as a string.
+func parseCron(value interface{}) (interface{}, error) {
+	text, ok := value.(string)
+	if !ok {
+		return nil, errors.New("value is not a cron expression string")
+	}
+	expression := strings.TrimSpace(text)
+
+	// Check the macros.
+	if strings.HasPrefix(expression, "@") {
+		if every, found := strings.CutPrefix(expression, "@every "); found {
+			if duration, err := time.ParseDuration(strings.TrimSpace(every)); err != nil || duration <= 0 {
+				return nil, fmt.Errorf("value is not a valid cron expression: invalid interval %q", every)
+			}
+			return expression, nil
+		}
+		if !contains(cronMacros, expression) {
+			return nil, fmt.Errorf("value is not a valid cron expression: unknown macro %q", expression)
+		}
+		return expression, nil
+	}
+
+	// Check every field of the expression.
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("value is not a valid cron expression: expected %d fields, got %d", len(cronFields), len(fields))
+	}
+	for index, field := range fields {
+		if err := checkCronField(field, cronFields[index].min, cronFields[index].max, cronFields[index].names); err != nil {
+			return nil, fmt.Errorf("value is not a valid cron expression: %s field: %w", cronFields[index].name, err)
+		}
+	}
+	return expression, nil
+}
+
+// checkCronField checks a comma separated list of values, ranges and steps of a cron field.
+func checkCronField(field string, min int, max int, names []string) error {
+	for _, item := range strings.Split(field, ",") {
+		// Split off the optional step.
+		item, step, hasStep := strings.Cut(item, "/")
+		if hasStep {
+			if number, err := strconv.Atoi(step); err != nil || number <= 0 {
+				return fmt.Errorf("invalid step %q", step)
+			}
+		}
+
+		// The wildcard covers the whole range.
+		if item == "*" {
+			continue
+		}
+
+		// Check both ends of a range, or the single value.
+		start, end, isRange := strings.Cut(item, "-")
+		low, err := parseCronValue(start, min, max, names)
+		if err != nil {
+			return err
+		}
+		if isRange {
+			high, err := parseCronValue(end, min, max, names)
+			if err != nil {
+				return err
+			}
+			if high < low {
+				return fmt.Errorf("invalid range %q", item)
+			}
+		}
+	}
+	return nil
+}
+
+// parseCronValue parses a number or a name of a cron field and checks that it is within the range of the field.
+func parseCronValue(text string, min int, max int, names []string) (int, error) {
+	for index, name := range names {
+		if strings.EqualFold(text, name) {
+			return min + index, nil
+		}
+	}
+	number, err := strconv.Atoi(text)
+	if err != nil || number < min || number > max {
+		return 0, fmt.Errorf("value %q is not between %d and %d", text, min, max)
+	}
+	return number, nil
+}
+
+// isASCIILetterOrDigit reports whether a character is an ASCII letter or digit.
+func isASCIILetterOrDigit(char rune) bool {
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9')
+}
@@ -0,0 +1,254 @@
+package configmaster
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrKeyNotFound is wrapped by the errors of Lookup and the typed getters when a key does not exist in the configuration data.
+var ErrKeyNotFound = errors.New("key not found")
+
+// TypeError is returned by the typed getters when a value cannot be converted to the requested type.
+type TypeError struct {
+	// Key is the dotted key that was requested.
+	Key string
+	// Want is the name of the requested type.
+	Want string
+	// Value is the stored value that could not be converted.
+	Value interface{}
+}
+
+// Error returns a description of the failed conversion naming the key and the actual type.
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("key %q: cannot convert %T value %v to %s", e.Key, e.Value, e.Value, e.Want)
+}
+
+// GetString retrieves a string value by its key.
+func (c *Config) GetString(key string) (string, error) {
+	value, err := c.lookupValue(key)
+	if err != nil {
+		return "", err
+	}
+	result, ok := toString(value)
+	if !ok {
+		return "", &TypeError{Key: key, Want: "string", Value: value}
+	}
+	return result, nil
+}
+
+// GetInt retrieves an int value by its key, converting whole float64 numbers and numeric strings.
+func (c *Config) GetInt(key string) (int, error) {
+	value, err := c.lookupValue(key)
+	if err != nil {
+		return 0, err
+	}
+	result, ok := toInt(value)
+	if !ok {
+		return 0, &TypeError{Key: key, Want: "int", Value: value}
+	}
+	return result, nil
+}
+
+// GetBool retrieves a bool value by its key, converting strings such as "true" or "0".
+func (c *Config) GetBool(key string) (bool, error) {
+	value, err := c.lookupValue(key)
+	if err != nil {
+		return false, err
+	}
+	result, ok := toBool(value)
+	if !ok {
+		return false, &TypeError{Key: key, Want: "bool", Value: value}
+	}
+	return result, nil
+}
+
+// GetFloat64 retrieves a float64 value by its key, converting integers and numeric strings.
+func (c *Config) GetFloat64(key string) (float64, error) {
+	value, err := c.lookupValue(key)
+	if err != nil {
+		return 0, err
+	}
+	result, ok := toFloat64(value)
+	if !ok {
+		return 0, &TypeError{Key: key, Want: "float64", Value: value}
+	}
+	return result, nil
+}
+
+// GetDuration retrieves a time.Duration value by its key.
+// Strings are parsed with time.ParseDuration and whole numbers are taken as nanoseconds.
+func (c *Config) GetDuration(key string) (time.Duration, error) {
+	value, err := c.lookupValue(key)
+	if err != nil {
+		return 0, err
+	}
+	result, ok := toDuration(value)
+	if !ok {
+		return 0, &TypeError{Key: key, Want: "time.Duration", Value: value}
+	}
+	return result, nil
+}
+
+// MustGetString is like GetString but panics if the key is missing or cannot be converted.
+func (c *Config) MustGetString(key string) string {
+	return must(c.GetString(key))
+}
+
+// MustGetInt is like GetInt but panics if the key is missing or cannot be converted.
+func (c *Config) MustGetInt(key string) int {
+	return must(c.GetInt(key))
+}
+
+// MustGetBool is like GetBool but panics if the key is missing or cannot be converted.
+func (c *Config) MustGetBool(key string) bool {
+	return must(c.GetBool(key))
+}
+
+// MustGetFloat64 is like GetFloat64 but panics if the key is missing or cannot be converted.
+func (c *Config) MustGetFloat64(key string) float64 {
+	return must(c.GetFloat64(key))
+}
+
+// MustGetDuration is like GetDuration but panics if the key is missing or cannot be converted.
+func (c *Config) MustGetDuration(key string) time.Duration {
+	return must(c.GetDuration(key))
+}
+
+// lookupValue retrieves a value by its key, returning a PathError that wraps ErrKeyNotFound if it does not exist.
+func (c *Config) lookupValue(key string) (interface{}, error) {
+	value, err := c.Lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, &PathError{Path: key, At: key, Err: fmt.Errorf("%w: value is null", ErrKeyNotFound)}
+	}
+	return value, nil
+}
+
+// must panics if err is not nil and returns the value otherwise.
+func must[T any](value T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// toString converts a stored value into a string.
+// Values converted by the semantic formats, such as URLs and IP addresses, are converted back into their text.
+func toString(value interface{}) (string, bool) {
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue, true
+	case time.Time:
+		return typedValue.Format(time.RFC3339Nano), true
+	case fmt.Stringer:
+		return typedValue.String(), true
+	default:
+		return "", false
+	}
+}
+
+// toInt converts a stored value into an int.
+func toInt(value interface{}) (int, bool) {
+	switch typedValue := value.(type) {
+	case int:
+		return typedValue, true
+	case int8:
+		return int(typedValue), true
+	case int16:
+		return int(typedValue), true
+	case int32:
+		return int(typedValue), true
+	case int64:
+		if typedValue < math.MinInt || typedValue > math.MaxInt {
+			return 0, false
+		}
+		return int(typedValue), true
+	case uint:
+		if typedValue > math.MaxInt {
+			return 0, false
+		}
+		return int(typedValue), true
+	case uint8:
+		return int(typedValue), true
+	case uint16:
+		return int(typedValue), true
+	case uint32:
+		if uint64(typedValue) > math.MaxInt {
+			return 0, false
+		}
+		return int(typedValue), true
+	case uint64:
+		if typedValue > math.MaxInt {
+			return 0, false
+		}
+		return int(typedValue), true
+	case float32:
+		return toInt(float64(typedValue))
+	case float64:
+		// Only whole numbers that fit into an int are converted.
+		if typedValue != math.Trunc(typedValue) || typedValue < math.MinInt || typedValue >= math.MaxInt {
+			return 0, false
+		}
+		return int(typedValue), true
+	case string:
+		result, err := strconv.Atoi(strings.TrimSpace(typedValue))
+		return result, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// toFloat64 converts a stored value into a float64.
+func toFloat64(value interface{}) (float64, bool) {
+	switch typedValue := value.(type) {
+	case float64:
+		return typedValue, true
+	case float32:
+		return float64(typedValue), true
+	case string:
+		result, err := strconv.ParseFloat(strings.TrimSpace(typedValue), 64)
+		return result, err == nil
+	default:
+		// Any integer is widened to float64.
+		if result, ok := toInt(value); ok {
+			return float64(result), true
+		}
+		return 0, false
+	}
+}
+
+// toBool converts a stored value into a bool.
+func toBool(value interface{}) (bool, bool) {
+	switch typedValue := value.(type) {
+	case bool:
+		return typedValue, true
+	case string:
+		result, err := strconv.ParseBool(strings.TrimSpace(typedValue))
+		return result, err == nil
+	default:
+		return false, false
+	}
+}
+
+// toDuration converts a stored value into a time.Duration.
+func toDuration(value interface{}) (time.Duration, bool) {
+	switch typedValue := value.(type) {
+	case time.Duration:
+		return typedValue, true
+	case string:
+		result, err := time.ParseDuration(strings.TrimSpace(typedValue))
+		return result, err == nil
+	default:
+		// Whole numbers are taken as nanoseconds, the same as converting them with time.Duration.
+		if result, ok := toInt(value); ok {
+			return time.Duration(result), true
+		}
+		return 0, false
+	}
+}
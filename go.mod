@@ -1,3 +1,8 @@
 module github.com/akshay-k-akshay/config-master
 
 go 1.23.0
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,555 @@
+package configmaster
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// JSON5SyntaxError describes a syntax error found while parsing a JSON5 document.
+type JSON5SyntaxError struct {
+	// Line is the 1-based line of the error.
+	Line int
+	// Column is the 1-based column of the error, counted in characters.
+	Column int
+	// Msg describes the error.
+	Msg string
+}
+
+// Error returns the error message prefixed with the position of the error.
+func (e *JSON5SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// json5Parser is a recursive descent parser for JSON5 documents.
+type json5Parser struct {
+	data string
+	pos  int
+	// path is the dotted path of the value being parsed.
+	path string
+	// lines records the line of every key by dotted path when it is not nil.
+	lines map[string]int
+	// newlines holds the offsets of the line breaks, computed when lines are recorded.
+	newlines []int
+}
+
+// parseJSON5 parses JSON5 configuration data into the same map shape produced by the JSON decoder.
+func parseJSON5(byteValue []byte) (map[string]interface{}, error) {
+	return newJSON5Parser(byteValue).parseDocument()
+}
+
+// json5LineIndex returns the line of every key of a JSON or JSON5 document by dotted path, or nil if it cannot be parsed.
+func json5LineIndex(byteValue []byte) map[string]int {
+	parser := newJSON5Parser(byteValue)
+	parser.lines = make(map[string]int)
+	for index := 0; index < len(parser.data); index++ {
+		if parser.data[index] == '\n' {
+			parser.newlines = append(parser.newlines, index)
+		}
+	}
+	if _, err := parser.parseDocument(); err != nil {
+		return nil
+	}
+	return parser.lines
+}
+
+// newJSON5Parser returns a parser for the document, skipping a leading byte order mark.
+func newJSON5Parser(byteValue []byte) *json5Parser {
+	return &json5Parser{data: strings.TrimPrefix(string(byteValue), "\uFEFF")}
+}
+
+// parseDocument parses the top-level object of the document.
+func (p *json5Parser) parseDocument() (map[string]interface{}, error) {
+	// Parse the top-level value.
+	if err := p.skipWhitespace(); err != nil {
+		return nil, err
+	}
+	start := p.pos
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure nothing but whitespace and comments follows the top-level value.
+	if err := p.skipWhitespace(); err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.data) {
+		return nil, p.errorf(p.pos, "unexpected %s after top-level value", p.describe(p.pos))
+	}
+
+	// The configuration must be an object, null is treated like an empty document the same as the JSON decoder does.
+	switch config := value.(type) {
+	case map[string]interface{}:
+		return config, nil
+	case nil:
+		return nil, nil
+	default:
+		return nil, p.errorf(start, "top-level value must be an object, got %T", value)
+	}
+}
+
+// record remembers the line of the value found at the given path when lines are recorded.
+func (p *json5Parser) record(path string, pos int) {
+	if p.lines != nil {
+		p.lines[path] = sort.SearchInts(p.newlines, pos) + 1
+	}
+}
+
+// errorf returns a JSON5SyntaxError for the given byte offset.
+func (p *json5Parser) errorf(pos int, format string, args ...interface{}) error {
+	// Count the lines and columns up to the offset.
+	line, column := 1, 1
+	for index, char := range p.data[:pos] {
+		switch {
+		case char == '\n':
+			line, column = line+1, 1
+		case char == '\r' && !strings.HasPrefix(p.data[index+1:], "\n"):
+			line, column = line+1, 1
+		case char == '\u2028' || char == '\u2029':
+			line, column = line+1, 1
+		case char != '\r':
+			column++
+		}
+	}
+	return &JSON5SyntaxError{Line: line, Column: column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// describe returns a description of the character at the given offset for error messages.
+func (p *json5Parser) describe(pos int) string {
+	if pos >= len(p.data) {
+		return "end of input"
+	}
+	char, _ := utf8.DecodeRuneInString(p.data[pos:])
+	return fmt.Sprintf("character %q", char)
+}
+
+// peek returns the character at the current position without consuming it.
+func (p *json5Parser) peek() rune {
+	if p.pos >= len(p.data) {
+		return -1
+	}
+	char, _ := utf8.DecodeRuneInString(p.data[p.pos:])
+	return char
+}
+
+// next consumes and returns the character at the current position.
+func (p *json5Parser) next() rune {
+	if p.pos >= len(p.data) {
+		return -1
+	}
+	char, size := utf8.DecodeRuneInString(p.data[p.pos:])
+	p.pos += size
+	return char
+}
+
+// skipWhitespace skips whitespace, line comments and block comments.
+func (p *json5Parser) skipWhitespace() error {
+	for p.pos < len(p.data) {
+		char := p.peek()
+		switch {
+		case isJSON5Whitespace(char):
+			p.next()
+		case strings.HasPrefix(p.data[p.pos:], "//"):
+			// Skip a line comment up to the end of the line.
+			for p.pos < len(p.data) && !isJSON5LineTerminator(p.peek()) {
+				p.next()
+			}
+		case strings.HasPrefix(p.data[p.pos:], "/*"):
+			// Skip a block comment up to the closing marker.
+			end := strings.Index(p.data[p.pos+2:], "*/")
+			if end < 0 {
+				return p.errorf(p.pos, "unterminated block comment")
+			}
+			p.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// parseValue parses any JSON5 value at the current position.
+func (p *json5Parser) parseValue() (interface{}, error) {
+	char := p.peek()
+	switch {
+	case char == '{':
+		return p.parseObject()
+	case char == '[':
+		return p.parseArray()
+	case char == '"' || char == '\'':
+		return p.parseString()
+	case char == '-' || char == '+' || char == '.' || (char >= '0' && char <= '9'):
+		return p.parseNumber()
+	case char == 'I' || char == 'N':
+		return p.parseNumber()
+	case isJSON5IdentifierStart(char):
+		// Only the literals true, false and null are valid identifiers in value position.
+		start := p.pos
+		identifier, err := p.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		switch identifier {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return nil, p.errorf(start, "unexpected identifier %q", identifier)
+	default:
+		return nil, p.errorf(p.pos, "unexpected %s", p.describe(p.pos))
+	}
+}
+
+// parseObject parses an object, allowing unquoted keys and a trailing comma.
+func (p *json5Parser) parseObject() (map[string]interface{}, error) {
+	// Consume the opening brace.
+	p.next()
+	object := make(map[string]interface{})
+
+	for {
+		if err := p.skipWhitespace(); err != nil {
+			return nil, err
+		}
+
+		// The object ends after the closing brace, which may follow a trailing comma.
+		if p.peek() == '}' {
+			p.next()
+			return object, nil
+		}
+
+		// Parse the key, which is either a string or an identifier.
+		keyStart := p.pos
+		var key string
+		var err error
+		switch char := p.peek(); {
+		case char == '"' || char == '\'':
+			key, err = p.parseString()
+		case isJSON5IdentifierStart(char) || char == '\\':
+			key, err = p.parseIdentifier()
+		default:
+			return nil, p.errorf(p.pos, "expected object key, got %s", p.describe(p.pos))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Parse the colon separating the key from the value.
+		if err := p.skipWhitespace(); err != nil {
+			return nil, err
+		}
+		if p.peek() != ':' {
+			return nil, p.errorf(p.pos, "expected ':' after object key, got %s", p.describe(p.pos))
+		}
+		p.next()
+
+		// Parse the value below the path of its key.
+		if err := p.skipWhitespace(); err != nil {
+			return nil, err
+		}
+		parentPath := p.path
+		p.path = joinPath(parentPath, key)
+		p.record(p.path, keyStart)
+		object[key], err = p.parseValue()
+		p.path = parentPath
+		if err != nil {
+			return nil, err
+		}
+
+		// Expect either a comma or the closing brace.
+		if err := p.skipWhitespace(); err != nil {
+			return nil, err
+		}
+		switch p.peek() {
+		case ',':
+			p.next()
+		case '}':
+			p.next()
+			return object, nil
+		default:
+			return nil, p.errorf(p.pos, "expected ',' or '}' in object, got %s", p.describe(p.pos))
+		}
+	}
+}
+
+// parseArray parses an array, allowing a trailing comma.
+func (p *json5Parser) parseArray() ([]interface{}, error) {
+	// Consume the opening bracket.
+	p.next()
+	array := make([]interface{}, 0)
+
+	for {
+		if err := p.skipWhitespace(); err != nil {
+			return nil, err
+		}
+
+		// The array ends after the closing bracket, which may follow a trailing comma.
+		if p.peek() == ']' {
+			p.next()
+			return array, nil
+		}
+
+		// Parse the item below the indexed path of the array.
+		parentPath := p.path
+		p.path = fmt.Sprintf("%s[%d]", parentPath, len(array))
+		p.record(p.path, p.pos)
+		item, err := p.parseValue()
+		p.path = parentPath
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, item)
+
+		// Expect either a comma or the closing bracket.
+		if err := p.skipWhitespace(); err != nil {
+			return nil, err
+		}
+		switch p.peek() {
+		case ',':
+			p.next()
+		case ']':
+			p.next()
+			return array, nil
+		default:
+			return nil, p.errorf(p.pos, "expected ',' or ']' in array, got %s", p.describe(p.pos))
+		}
+	}
+}
+
+// parseString parses a single or double quoted string.
+func (p *json5Parser) parseString() (string, error) {
+	start := p.pos
+	quote := p.next()
+	var builder strings.Builder
+
+	for {
+		char := p.next()
+		switch {
+		case char == -1:
+			return "", p.errorf(start, "unterminated string")
+		case char == quote:
+			return builder.String(), nil
+		case char == '\n' || char == '\r':
+			return "", p.errorf(p.pos-1, "unescaped line break in string")
+		case char == '\\':
+			if err := p.parseEscape(&builder); err != nil {
+				return "", err
+			}
+		default:
+			builder.WriteRune(char)
+		}
+	}
+}
+
+// parseEscape parses the escape sequence following a backslash in a string.
+func (p *json5Parser) parseEscape(builder *strings.Builder) error {
+	start := p.pos - 1
+	char := p.next()
+	switch char {
+	case -1:
+		return p.errorf(start, "unterminated escape sequence")
+	case 'b':
+		builder.WriteByte('\b')
+	case 'f':
+		builder.WriteByte('\f')
+	case 'n':
+		builder.WriteByte('\n')
+	case 'r':
+		builder.WriteByte('\r')
+	case 't':
+		builder.WriteByte('\t')
+	case 'v':
+		builder.WriteByte('\v')
+	case '0':
+		// A null character must not be followed by a digit.
+		if next := p.peek(); next >= '0' && next <= '9' {
+			return p.errorf(start, "octal escape sequences are not allowed")
+		}
+		builder.WriteByte(0)
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return p.errorf(start, "invalid escape sequence \\%c", char)
+	case 'x':
+		value, err := p.parseHex(2)
+		if err != nil {
+			return err
+		}
+		builder.WriteRune(rune(value))
+	case 'u':
+		value, err := p.parseUnicodeEscape()
+		if err != nil {
+			return err
+		}
+		builder.WriteRune(value)
+	case '\r':
+		// Line continuations are removed from the string, including a \r\n pair.
+		if p.peek() == '\n' {
+			p.next()
+		}
+	case '\n', '\u2028', '\u2029':
+		// Line continuations are removed from the string.
+	default:
+		// Any other escaped character stands for itself.
+		builder.WriteRune(char)
+	}
+	return nil
+}
+
+// parseUnicodeEscape parses the hex digits of a \u escape, combining surrogate pairs.
+func (p *json5Parser) parseUnicodeEscape() (rune, error) {
+	value, err := p.parseHex(4)
+	if err != nil {
+		return 0, err
+	}
+
+	// Combine a high surrogate with the low surrogate escape that follows it.
+	if value >= 0xD800 && value < 0xDC00 && strings.HasPrefix(p.data[p.pos:], "\\u") {
+		saved := p.pos
+		p.pos += 2
+		low, err := p.parseHex(4)
+		if err == nil && low >= 0xDC00 && low < 0xE000 {
+			return rune((value-0xD800)<<10+(low-0xDC00)) + 0x10000, nil
+		}
+		p.pos = saved
+	}
+	return rune(value), nil
+}
+
+// parseHex parses a fixed number of hex digits.
+func (p *json5Parser) parseHex(digits int) (uint64, error) {
+	start := p.pos
+	if p.pos+digits > len(p.data) {
+		return 0, p.errorf(start, "expected %d hex digits", digits)
+	}
+	value, err := strconv.ParseUint(p.data[p.pos:p.pos+digits], 16, 32)
+	if err != nil {
+		return 0, p.errorf(start, "expected %d hex digits", digits)
+	}
+	p.pos += digits
+	return value, nil
+}
+
+// parseIdentifier parses an ECMAScript identifier name used as an unquoted key or literal.
+func (p *json5Parser) parseIdentifier() (string, error) {
+	start := p.pos
+	var builder strings.Builder
+
+	for p.pos < len(p.data) {
+		char := p.peek()
+		switch {
+		case char == '\\':
+			// Identifiers may contain unicode escapes.
+			p.next()
+			if p.next() != 'u' {
+				return "", p.errorf(p.pos-1, "expected unicode escape in identifier")
+			}
+			value, err := p.parseUnicodeEscape()
+			if err != nil {
+				return "", err
+			}
+			builder.WriteRune(value)
+		case isJSON5IdentifierStart(char) || (builder.Len() > 0 && isJSON5IdentifierPart(char)):
+			builder.WriteRune(p.next())
+		default:
+			if builder.Len() == 0 {
+				return "", p.errorf(start, "expected identifier, got %s", p.describe(start))
+			}
+			return builder.String(), nil
+		}
+	}
+	return builder.String(), nil
+}
+
+// parseNumber parses a decimal or hexadecimal number, Infinity or NaN.
+func (p *json5Parser) parseNumber() (float64, error) {
+	start := p.pos
+
+	// Parse the optional sign.
+	sign := 1.0
+	switch p.peek() {
+	case '-':
+		sign = -1
+		p.next()
+	case '+':
+		p.next()
+	}
+
+	// Parse the special values.
+	switch {
+	case strings.HasPrefix(p.data[p.pos:], "Infinity"):
+		p.pos += len("Infinity")
+		return math.Inf(int(sign)), nil
+	case strings.HasPrefix(p.data[p.pos:], "NaN"):
+		p.pos += len("NaN")
+		return math.NaN(), nil
+	}
+
+	// Parse hexadecimal integers.
+	if strings.HasPrefix(p.data[p.pos:], "0x") || strings.HasPrefix(p.data[p.pos:], "0X") {
+		p.pos += 2
+		digitsStart := p.pos
+		for p.pos < len(p.data) && isHexDigit(p.data[p.pos]) {
+			p.pos++
+		}
+		value, err := strconv.ParseUint(p.data[digitsStart:p.pos], 16, 64)
+		if err != nil {
+			return 0, p.errorf(start, "invalid hexadecimal number %q", p.data[start:p.pos])
+		}
+		return sign * float64(value), nil
+	}
+
+	// Parse the integer part, the fraction and the exponent of a decimal number.
+	digitsStart := p.pos
+	for p.pos < len(p.data) && strings.IndexByte("0123456789.eE", p.data[p.pos]) >= 0 {
+		// Signs are only allowed right after the exponent marker.
+		p.pos++
+		if (p.data[p.pos-1] == 'e' || p.data[p.pos-1] == 'E') && p.pos < len(p.data) && (p.data[p.pos] == '+' || p.data[p.pos] == '-') {
+			p.pos++
+		}
+	}
+	literal := p.data[digitsStart:p.pos]
+	if literal == "" || literal == "." || (len(literal) > 1 && literal[0] == '0' && literal[1] >= '0' && literal[1] <= '9') {
+		return 0, p.errorf(start, "invalid number %q", p.data[start:p.pos])
+	}
+	value, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return 0, p.errorf(start, "invalid number %q", p.data[start:p.pos])
+	}
+	return sign * value, nil
+}
+
+// isHexDigit reports whether a byte is a hexadecimal digit.
+func isHexDigit(char byte) bool {
+	return (char >= '0' && char <= '9') || (char >= 'a' && char <= 'f') || (char >= 'A' && char <= 'F')
+}
+
+// isJSON5Whitespace reports whether a character is whitespace in JSON5.
+func isJSON5Whitespace(char rune) bool {
+	switch char {
+	case '\t', '\n', '\v', '\f', '\r', ' ', '\u00A0', '\u2028', '\u2029', '\uFEFF':
+		return true
+	}
+	return unicode.Is(unicode.Zs, char)
+}
+
+// isJSON5LineTerminator reports whether a character ends a line in JSON5.
+func isJSON5LineTerminator(char rune) bool {
+	return char == '\n' || char == '\r' || char == '\u2028' || char == '\u2029'
+}
+
+// isJSON5IdentifierStart reports whether a character can start an identifier.
+func isJSON5IdentifierStart(char rune) bool {
+	return char == '$' || char == '_' || unicode.IsLetter(char) || unicode.Is(unicode.Nl, char)
+}
+
+// isJSON5IdentifierPart reports whether a character can continue an identifier.
+func isJSON5IdentifierPart(char rune) bool {
+	return isJSON5IdentifierStart(char) || unicode.IsDigit(char) || unicode.Is(unicode.Mn, char) ||
+		unicode.Is(unicode.Mc, char) || unicode.Is(unicode.Pc, char) || char == '\u200C' || char == '\u200D'
+}
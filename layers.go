@@ -0,0 +1,41 @@
+package configmaster
+
+import "fmt"
+
+// layerValue is a value for a schema key loaded from one of the value sources.
+type layerValue struct {
+	value  interface{}
+	source Source
+}
+
+// loadValues parses the value sources in order and merges them, later sources overriding the keys of earlier ones.
+// It returns the merged values together with the source of every key.
+func (c *Config) loadValues() (map[string]interface{}, map[string]Source, error) {
+	merged := make(map[string]interface{})
+	origins := make(map[string]Source)
+	for index, source := range c.valueSources {
+		// Parse the source the same way as the schema input.
+		values, err := c.parseInput(source)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error loading values: %w", err)
+		}
+
+		// Merge the values over the ones loaded so far, remembering where every key came from.
+		c.mergeValues("", merged, values, c.valueSource(index, source), origins)
+	}
+	return merged, origins, nil
+}
+
+// valueSource returns a function describing where a key of a value source came from.
+// Keys of files are traced to their line, keys of maps to the position of the map in WithValues.
+func (c *Config) valueSource(index int, source interface{}) func(string) Source {
+	if filename, ok := source.(string); ok {
+		return func(path string) Source {
+			return Source{Kind: SourceFile, Name: filename, Line: c.fileLines[filename][path]}
+		}
+	}
+	name := fmt.Sprintf("values[%d]", index)
+	return func(string) Source {
+		return Source{Kind: SourceValues, Name: name}
+	}
+}
@@ -0,0 +1,167 @@
+package configmaster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// lintSchema checks the schema for malformed leaves before any value is resolved and reports every problem found.
+func (c *Config) lintSchema() error {
+	var schemaErrors ValidationErrors
+	c.lintMap("", c.schema, &schemaErrors)
+	if len(schemaErrors) == 0 {
+		return nil
+	}
+
+	// Sort the errors by key so that the report is stable.
+	sort.SliceStable(schemaErrors, func(i, j int) bool {
+		return schemaErrors[i].Key < schemaErrors[j].Key
+	})
+	return schemaErrors
+}
+
+// lintMap checks a single level of the schema found at the given path, walking it the same way as processMap.
+func (c *Config) lintMap(path string, schema map[string]interface{}, schemaErrors *ValidationErrors) {
+	for key, value := range schema {
+		keyPath := joinPath(path, key)
+		switch typedValue := value.(type) {
+		case map[string]interface{}:
+			if !isSchemaLeaf(typedValue) {
+				c.lintNamespace(keyPath, typedValue, schemaErrors)
+				c.lintMap(keyPath, typedValue, schemaErrors)
+				continue
+			}
+			c.lintLeaf(keyPath, typedValue, schemaErrors)
+		case []interface{}:
+			// Check the maps found in slices like processMap does.
+			for index, item := range typedValue {
+				if nestedItem, ok := item.(map[string]interface{}); ok {
+					c.lintMap(fmt.Sprintf("%s[%d]", keyPath, index), nestedItem, schemaErrors)
+				}
+			}
+		}
+	}
+}
+
+// lintNamespace reports the keys of a namespace that are named like schema properties, unless they hold leaves or namespaces:
+// properties next to nested keys, which make a leaf be read as a namespace, and objects without any schema property,
+// which are more likely to be malformed leaves.
+func (c *Config) lintNamespace(path string, namespace map[string]interface{}, schemaErrors *ValidationErrors) {
+	keys := make([]string, 0, len(namespace))
+	for key := range namespace {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var err error
+		nested, isMap := namespace[key].(map[string]interface{})
+		switch {
+		case isSchemaProperty(key, namespace[key]):
+			err = fmt.Errorf("ambiguous node: %q is named like a schema property but is read as a plain value of a namespace", key)
+		case isNestedKey(key, namespace[key]) && isMap && !isSchemaLeaf(nested):
+			err = fmt.Errorf("ambiguous node: %q is named like a schema property but holds an object without schema properties", key)
+		default:
+			continue
+		}
+		source := Source{Kind: SourceSchema, Name: c.schemaFile, Line: c.fileLines[c.schemaFile][joinPath(path, key)]}
+		*schemaErrors = append(*schemaErrors, &ValidationError{Key: path, Value: namespace[key], Source: source, Rule: "schema", Err: err})
+	}
+}
+
+// isNestedKey checks if a key named like a schema property holds an object, which makes it a nested key instead of a property.
+func isNestedKey(key string, value interface{}) bool {
+	_, isMap := value.(map[string]interface{})
+	return isMap && contains(schemaProperties, key) && !isSchemaProperty(key, value)
+}
+
+// lintLeaf checks the properties of a schema leaf.
+func (c *Config) lintLeaf(path string, leaf map[string]interface{}, schemaErrors *ValidationErrors) {
+	// report adds a schema error pointing at the line of the property in the schema file.
+	report := func(property string, value interface{}, err error) {
+		source := Source{Kind: SourceSchema, Name: c.schemaFile, Line: c.fileLines[c.schemaFile][joinPath(path, property)]}
+		*schemaErrors = append(*schemaErrors, &ValidationError{Key: path, Value: value, Source: source, Rule: "schema", Err: err})
+	}
+
+	// Check for properties that are not supported, sorted so that the report is stable.
+	properties := make([]string, 0, len(leaf))
+	for property := range leaf {
+		properties = append(properties, property)
+	}
+	sort.Strings(properties)
+	for _, property := range properties {
+		if !contains(schemaProperties, property) {
+			report(property, leaf[property], fmt.Errorf("unsupported schema property %q", property))
+		}
+	}
+
+	// Check the types of the properties.
+	if env, exists := leaf["env"]; exists {
+		if _, ok := env.(string); !ok {
+			report("env", env, fmt.Errorf(`"env" must be a string, got %T`, env))
+		}
+	}
+	for _, property := range []string{"required", "nullable"} {
+		if flag, exists := leaf[property]; exists {
+			if _, ok := flag.(bool); !ok {
+				report(property, flag, fmt.Errorf("%q must be a bool, got %T", property, flag))
+			}
+		}
+	}
+
+	// Check the schema of the items of arrays, which only applies to the array format.
+	format, hasFormat := leaf["format"]
+	if items, hasItems := leaf["items"]; hasItems {
+		itemsPath := joinPath(path, "items")
+		switch itemsSchema := items.(type) {
+		case map[string]interface{}:
+			if isSchemaLeaf(itemsSchema) {
+				c.lintLeaf(itemsPath, itemsSchema, schemaErrors)
+			} else {
+				c.lintMap(itemsPath, itemsSchema, schemaErrors)
+			}
+		default:
+			report("items", items, fmt.Errorf(`"items" must be a schema object, got %T`, items))
+		}
+		if formatName, ok := format.(string); hasFormat && (!ok || !strings.EqualFold(formatName, "array")) {
+			report("items", items, fmt.Errorf(`"items" requires the "array" format, got %v`, format))
+		}
+	}
+
+	// Check that the format is known and that the default matches it.
+	if !hasFormat {
+		return
+	}
+	switch typedFormat := format.(type) {
+	case []interface{}:
+	case string:
+		if !c.isKnownFormat(typedFormat) {
+			report("format", format, fmt.Errorf("unknown format %q", typedFormat))
+			return
+		}
+	default:
+		report("format", format, fmt.Errorf(`"format" must be a format name or a list of values, got %T`, format))
+		return
+	}
+	defaultValue, hasDefault := leaf["default"]
+	nullable, _ := leaf["nullable"].(bool)
+	if !hasDefault || (defaultValue == nil && nullable) {
+		return
+	}
+	if _, err := c.formatValue(normalizeNumber(defaultValue, format), format); err != nil {
+		report("default", defaultValue, fmt.Errorf("default does not match the format %v: %w", format, err))
+	}
+}
+
+// isKnownFormat reports whether a format name is built in or registered.
+func (c *Config) isKnownFormat(name string) bool {
+	name = strings.ToLower(name)
+	if contains(builtinFormats, name) {
+		return true
+	}
+	if _, exists := c.formats[name]; exists {
+		return true
+	}
+	_, exists := lookupFormat(name)
+	return exists
+}
@@ -6,43 +6,108 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Config holds the configuration data.
+// It is safe for concurrent use: readers see an immutable snapshot of the resolved values that Set, Delete, Merge and Reload replace as a whole.
 type Config struct {
-	data map[string]interface{}
+	input           interface{}
+	opts            []Option
+	fileFormat      FileFormat
+	json5           bool
+	envSource       EnvSource
+	env             EnvSource
+	dotEnvFiles     []string
+	envPrecedence   EnvPrecedence
+	valueSources    []interface{}
+	sliceStrategies map[string]SliceStrategy
+	formats         map[string]FormatFunc
+	strictSchema    bool
+	unknownKeys     UnknownKeyMode
+	warnedKeys      map[string]bool
+	logger          *log.Logger
+	pollInterval    time.Duration
+	schema          map[string]interface{}
+	schemaFile      string
+	fileLines       map[string]map[string]int
+	frozen          bool
+	mu              sync.Mutex
+	state           atomic.Pointer[snapshot]
 }
 
 // NewConfig creates a new Config instance from various input types (file path or map).
-func NewConfig(input interface{}) (*Config, error) {
+func NewConfig(input interface{}, opts ...Option) (*Config, error) {
+	// Create a new Config instance and apply the options, remembering them for Reload.
+	cfg := &Config{input: input, opts: opts}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	// Use the process environment unless another environment source was given.
+	if cfg.envSource == nil {
+		cfg.envSource = OSEnv{}
+	}
+
+	// Load the variables of the .env files and layer them with the environment source.
+	dotEnv, err := loadDotEnvFiles(cfg.dotEnvFiles)
+	if err != nil {
+		return nil, err
+	}
+	cfg.env = layerDotEnv(cfg.envSource, dotEnv, cfg.envPrecedence)
+
 	// Parse the input to extract configuration data.
-	config, err := parseInput(input)
+	config, err := cfg.parseInput(input)
 	if err != nil {
 		return nil, err
 	}
+	cfg.schema = config
 
-	// Create a new Config instance with the parsed configuration data.
-	cfg := &Config{data: config}
+	// Remember the schema file so that defaults can be traced back to it.
+	if filename, ok := input.(string); ok {
+		cfg.schemaFile = filename
+	}
+
+	// Check the schema for malformed leaves when requested.
+	if cfg.strictSchema {
+		if err := cfg.lintSchema(); err != nil {
+			return nil, fmt.Errorf("[Config-Master]: invalid schema: %w", err)
+		}
+	}
+
+	// Load the value sources that are merged over the defaults of the schema.
+	values, origins, err := cfg.loadValues()
+	if err != nil {
+		return nil, err
+	}
 
 	// Process the configuration data recursively to resolve any nested maps and validate the data against the expected formats.
-	cfg.data, err = cfg.processRecursively(cfg.data)
+	data, sources, err := cfg.resolve(cfg.schema, values, origins)
 	if err != nil {
 		return nil, fmt.Errorf("[Config-Master]: %w", err)
 	}
+	cfg.state.Store(&snapshot{data: data, sources: sources, values: values, valueOrigins: origins})
 
 	return cfg, nil
 }
 
 // parseInput parses the input to extract configuration data.
-func parseInput(input interface{}) (map[string]interface{}, error) {
+func (c *Config) parseInput(input interface{}) (map[string]interface{}, error) {
 	// Check if the input is a string, map, or something else.
 	switch input := input.(type) {
 	case string:
-		// If the input is a string, read the JSON configuration from the file and return it.
-		return parseFromFile(input)
+		// If the input is a string, read the configuration from the file and return it.
+		return c.parseFromFile(input)
 	case map[string]interface{}:
 		// If the input is a map, return it as is.
 		return input, nil
@@ -52,8 +117,9 @@ func parseInput(input interface{}) (map[string]interface{}, error) {
 	}
 }
 
-// parseFromFile reads and parses the JSON configuration from a file.
-func parseFromFile(filename string) (map[string]interface{}, error) {
+// parseFromFile reads and parses the configuration from a JSON, JSON5, YAML or TOML file.
+// Files are parsed with the format set by WithFileFormat, or with the format matching their extension.
+func (c *Config) parseFromFile(filename string) (map[string]interface{}, error) {
 	// Open the file and read its contents.
 	file, err := os.Open(filename)
 	if err != nil {
@@ -67,51 +133,127 @@ func parseFromFile(filename string) (map[string]interface{}, error) {
 		return nil, fmt.Errorf("error reading file: %w", err)
 	}
 
-	// Unmarshal the byte slice into a map.
+	// Detect the format from the file extension unless it was set explicitly.
+	format := c.fileFormat
+	if format == "" {
+		format = detectFileFormat(filename)
+	}
+
+	// Parse JSON files as JSON5 when requested.
+	if format == FormatJSON && c.json5 {
+		format = FormatJSON5
+	}
+
+	// Unmarshal the byte slice into a map using the decoder matching the format.
 	var config map[string]interface{}
-	err = json.Unmarshal(byteValue, &config)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing JSON from file: %w", err)
+	switch format {
+	case FormatJSON:
+		err = json.Unmarshal(byteValue, &config)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing JSON from file: %w", err)
+		}
+	case FormatJSON5:
+		config, err = parseJSON5(byteValue)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing JSON5 from file: %w", err)
+		}
+	case FormatYAML:
+		config, err = parseYAML(byteValue)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing YAML from file: %w", err)
+		}
+	case FormatTOML:
+		config, err = parseTOML(byteValue)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing TOML from file: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported file format: %q", format)
 	}
 
+	// Remember the line of every key so that values can be traced back to the file.
+	c.recordLines(filename, format, byteValue)
+
 	// Return the parsed configuration data.
 	return config, nil
 }
 
+// detectFileFormat returns the file format matching the extension of a file, defaulting to JSON.
+func detectFileFormat(filename string) FileFormat {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	case ".json5":
+		return FormatJSON5
+	default:
+		return FormatJSON
+	}
+}
+
+// normalizeValue converts the map, slice and integer types produced by the YAML and TOML decoders into the types used by the rest of the package.
+func normalizeValue(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		// Normalise every value of a string keyed map in place.
+		for key, nestedValue := range typedValue {
+			typedValue[key] = normalizeValue(nestedValue)
+		}
+		return typedValue
+	case map[interface{}]interface{}:
+		// Convert maps with non-string keys into string keyed maps.
+		normalizedMap := make(map[string]interface{}, len(typedValue))
+		for key, nestedValue := range typedValue {
+			normalizedMap[fmt.Sprint(key)] = normalizeValue(nestedValue)
+		}
+		return normalizedMap
+	case []map[string]interface{}:
+		// Convert arrays of tables into slices of maps.
+		normalizedSlice := make([]interface{}, len(typedValue))
+		for index, item := range typedValue {
+			normalizedSlice[index] = normalizeValue(item)
+		}
+		return normalizedSlice
+	case []interface{}:
+		// Normalise every item of a slice in place.
+		for index, item := range typedValue {
+			typedValue[index] = normalizeValue(item)
+		}
+		return typedValue
+	case int64:
+		return int(typedValue)
+	case uint64:
+		// Integers too large for int are kept as float64, the same as the JSON decoder does.
+		if typedValue > uint64(^uint(0)>>1) {
+			return float64(typedValue)
+		}
+		return int(typedValue)
+	default:
+		return value
+	}
+}
+
 // Get retrieves a value from the configuration data by its key.
+// Keys are paths like "qux.bar" or "xyz[1].bar" as described by Lookup, Get returns nil when the path cannot be found.
+// Maps and slices are returned as copies, so changing them does not change the configuration or its snapshots.
 func (c *Config) Get(key string) interface{} {
-	// Check if the key contains a dot separator.
-	if strings.Contains(key, ".") {
-		// If the key contains a dot separator, retrieve the nested value using the getNested method.
+	// Check if the key contains separators, indexes or escapes.
+	if strings.ContainsAny(key, `.[\`) {
+		// If the key is a path, retrieve the nested value using the getNested method.
 		return c.getNested(key)
 	}
-	// If the key does not contain a dot separator, retrieve the value from the top-level configuration data.
-	return c.data[key]
+	// If the key is a plain key, retrieve a copy of the value from the top-level configuration data.
+	return copyValue(c.current().data[key])
 }
 
 // getNested retrieves a nested value from the configuration data.
 func (c *Config) getNested(key string) interface{} {
-	// Split the key into parts based on the dot separator.
-	parts := strings.Split(key, ".")
-
-	// Start with the top-level configuration data.
-	var value interface{} = c.data
-
-	// Traverse through the configuration data using each part of the key.
-	for _, part := range parts {
-		// Attempt to access the next level of the configuration data.
-		mapValue, ok := value.(map[string]interface{})
-		if !ok {
-			// Return nil if any part of the key path is invalid.
-			return nil
-		}
-
-		// Check if the next part of the key is in the configuration data.
-		value, ok = mapValue[part]
-		if !ok || value == nil {
-			// Return nil if any part of the key path is invalid.
-			return nil
-		}
+	// Traverse the configuration data along the path of the key.
+	value, err := c.Lookup(key)
+	if err != nil {
+		// Return nil if any part of the key path is invalid.
+		return nil
 	}
 	// Return the final value found at the end of the key path.
 	return value
@@ -138,42 +280,203 @@ func getDefaultValue(config map[string]interface{}) interface{} {
 	return ""
 }
 
+// lookupEnv retrieves the value of an environment variable from the environment source of the configuration.
+func (c *Config) lookupEnv(key string) (string, bool) {
+	// Fall back to the process environment when no environment source was set up.
+	if c.env == nil {
+		return os.LookupEnv(key)
+	}
+	return c.env.LookupEnv(key)
+}
+
 // validateAndSetValue validates the configuration data against the expected format and sets the value accordingly.
-func validateAndSetValue(config map[string]interface{}) (interface{}, error) {
+// The value is taken from the environment variable if it is set, then from the value sources and finally from the default.
+// Keys marked "required" fail when none of them provides a value and only keys marked "nullable" accept null.
+// Values set with Set take precedence over the environment variable, and the value is then checked against its format and its constraints.
+// It returns the value together with the source it came from.
+func (c *Config) validateAndSetValue(config map[string]interface{}, override *layerValue) (interface{}, Source, error) {
 	// Initialize the value to an empty string.
 	var value interface{}
 
+	// Keep track of where the value came from for the validation report.
+	source := Source{Kind: SourceDefault}
+
 	// Check if the environment variable exists.
-	if envKey, exists := config["env"].(string); exists {
-		if envValue, exists := os.LookupEnv(envKey); exists {
-			// If the environment variable exists, set the value to the environment variable's value.
-			value = envValue
-		} else {
-			// If the environment variable does not exist, set the value to the default value.
-			value = getDefaultValue(config)
-		}
-	} else if _, exists := config["default"]; exists {
-		// If the default value exists, set the value to the default value.
+	envKey, hasEnv := config["env"].(string)
+	envValue, envExists := "", false
+	if hasEnv {
+		envValue, envExists = c.lookupEnv(envKey)
+	}
+
+	_, hasDefault := config["default"]
+	required, _ := config["required"].(bool)
+	nullable, _ := config["nullable"].(bool)
+
+	// Null values of the value sources only override the default of nullable keys.
+	// Values set with Set take precedence over the environment variable.
+	hasOverride := override != nil && (override.value != nil || nullable)
+	isSet := hasOverride && override.source.Kind == SourceSet
+	switch {
+	case envExists && !isSet:
+		// If the environment variable exists, parse its value into the type declared by the format.
+		// Keys with object or array defaults and no format take their value as JSON.
+		source = Source{Kind: SourceEnv, Name: envKey}
+		format, hasFormat := config["format"]
+		if !hasFormat {
+			format = structuredFormat(config["default"])
+		}
+		var err error
+		value, err = parseEnvValue(envKey, envValue, format)
+		if err != nil {
+			return nil, source, &ValidationError{Value: envValue, Source: source, Rule: "format", Err: err}
+		}
+	case hasOverride:
+		// If a value source sets the key, it overrides the default value.
+		source = override.source
+		value = override.value
+	case required && !hasDefault:
+		// If a required key is not set anywhere, report which environment variable should be set.
+		return nil, source, &ValidationError{Source: source, Rule: "required", Err: missingRequired(envKey)}
+	case nullable && !hasDefault:
+		// If a nullable key is not set anywhere, its value is null.
+		value = nil
+	case hasEnv || hasDefault:
+		// If the environment variable does not exist, set the value to the default value.
 		value = getDefaultValue(config)
-	} else {
-		// If the value is not in the expected format, return what we have.
-		return config, nil
+	default:
+		// If the leaf declares neither an environment variable nor a default, it has no value.
+		return nil, source, nil
+	}
+
+	// Null values are only accepted for nullable keys, a null required key counts as missing.
+	if value == nil {
+		switch {
+		case nullable:
+			return nil, source, nil
+		case required:
+			return nil, source, &ValidationError{Source: source, Rule: "required", Err: missingRequired(envKey)}
+		}
 	}
 
 	// Check if the expected format exists in the configuration data.
 	if expectedFormat, exists := config["format"]; exists {
-		// Check if the value is in the expected format.
-		if err := isValueInExpectedFormat(value, expectedFormat); err != nil {
-			return nil, err
+		// Convert numbers decoded from files into the numeric type declared by the format.
+		value = normalizeNumber(value, expectedFormat)
+
+		// Check if the value is in the expected format and convert it into the type of the format.
+		formattedValue, err := c.formatValue(value, expectedFormat)
+		if err != nil {
+			return nil, source, &ValidationError{Value: value, Source: source, Rule: "format", Err: err}
 		}
+		value = formattedValue
+	}
+
+	// Check the range, length and pattern constraints of the value.
+	if rule, err := checkConstraints(value, config); err != nil {
+		return nil, source, &ValidationError{Value: value, Source: source, Rule: rule, Err: err}
 	}
 
 	// Return the validated and set value.
+	return value, source, nil
+}
+
+// missingRequired returns the error reported for a required key without a value, naming the environment variable that sets it.
+func missingRequired(envKey string) error {
+	if envKey == "" {
+		return ErrRequired
+	}
+	return fmt.Errorf("%w, set the environment variable %s", ErrRequired, envKey)
+}
+
+// parseEnvValue converts the raw string of an environment variable into the type declared by the format.
+func parseEnvValue(envKey string, envValue string, format interface{}) (interface{}, error) {
+	value, err := parseStringValue(envValue, format)
+	if err != nil {
+		return nil, fmt.Errorf("environment variable %s: %w", envKey, err)
+	}
 	return value, nil
 }
 
+// structuredFormat returns the format matching an object or array default, or nil for every other default.
+func structuredFormat(defaultValue interface{}) interface{} {
+	switch defaultValue.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return nil
+	}
+}
+
+// parseStringValue converts a raw string into the type declared by the format.
+func parseStringValue(rawValue string, format interface{}) (interface{}, error) {
+	// Only the type name formats need parsing, enum lists are matched against the raw string.
+	formatName, ok := format.(string)
+	if !ok {
+		return rawValue, nil
+	}
+
+	// Parse the value according to the expected type.
+	switch strings.ToLower(formatName) {
+	case "int", "port", "nat":
+		parsed, err := strconv.Atoi(strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("invalid int value: %w", err)
+		}
+		return parsed, nil
+	case "float64":
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(rawValue), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float64 value: %w", err)
+		}
+		return parsed, nil
+	case "bool":
+		parsed, err := strconv.ParseBool(strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool value: %w", err)
+		}
+		return parsed, nil
+	case "object", "array":
+		// Objects and arrays are written as JSON.
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(rawValue), &parsed); err != nil {
+			return nil, fmt.Errorf("invalid JSON %s value: %w", strings.ToLower(formatName), err)
+		}
+		return parsed, nil
+	}
+
+	// Return the raw string for every other format.
+	return rawValue, nil
+}
+
+// normalizeNumber converts numbers decoded from files into the numeric type declared by the format.
+func normalizeNumber(value interface{}, format interface{}) interface{} {
+	// Only the numeric type name formats need normalising.
+	formatName, ok := format.(string)
+	if !ok {
+		return value
+	}
+
+	switch strings.ToLower(formatName) {
+	case "int", "port", "nat":
+		// JSON decodes every number as float64, so whole numbers are converted to int.
+		if number, ok := value.(float64); ok && number == math.Trunc(number) && math.Abs(number) <= 1<<53 {
+			return int(number)
+		}
+	case "float64":
+		// Integers are widened to float64.
+		if number, ok := value.(int); ok {
+			return float64(number)
+		}
+	}
+	return value
+}
+
 // isValueInExpectedFormat checks if a value is in the expected format.
-func isValueInExpectedFormat(value interface{}, format interface{}) error {
+// It returns the value converted into the Go type of the format, e.g. a time.Duration for "duration".
+// Format names that are not built in are looked up in the formats registered with RegisterFormat.
+func isValueInExpectedFormat(value interface{}, format interface{}) (interface{}, error) {
 	// Get the type of the value.
 	valueType := reflect.TypeOf(value)
 
@@ -183,7 +486,7 @@ func isValueInExpectedFormat(value interface{}, format interface{}) error {
 		// Check if the value is in the slice of expected formats.
 		if !contains(format, value) {
 			errorMessage := fmt.Sprintf("value is not in the expected format. Expected formats: %v", format)
-			return errors.New(errorMessage)
+			return nil, errors.New(errorMessage)
 		}
 	case string:
 		// Check if the value matches the expected format string.
@@ -191,90 +494,344 @@ func isValueInExpectedFormat(value interface{}, format interface{}) error {
 		case "string":
 
 			if valueType != reflect.TypeOf("") {
-				return errors.New("value is not a string")
+				return nil, errors.New("value is not a string")
 			}
 		case "bool":
 			if valueType != reflect.TypeOf(true) {
-				return errors.New("value is not a boolean")
+				return nil, errors.New("value is not a boolean")
 			}
 		case "float64":
 			if valueType != reflect.TypeOf(float64(0)) {
-				return errors.New("value is not a float64")
+				return nil, errors.New("value is not a float64")
 			}
 		case "int":
 			if valueType != reflect.TypeOf(int(0)) {
-				return errors.New("value is not an int")
+				return nil, errors.New("value is not an int")
 			}
+		case "object":
+			if _, ok := value.(map[string]interface{}); !ok {
+				return nil, errors.New("value is not an object")
+			}
+		case "array":
+			if _, ok := value.([]interface{}); !ok {
+				return nil, errors.New("value is not an array")
+			}
+		case "port":
+			return parsePort(value)
+		case "nat":
+			return parseNat(value)
+		case "url":
+			return parseURL(value)
+		case "email":
+			return parseEmail(value)
+		case "ipaddress":
+			return parseIPAddress(value, 0)
+		case "ipv4":
+			return parseIPAddress(value, 4)
+		case "ipv6":
+			return parseIPAddress(value, 6)
+		case "hostname":
+			return parseHostname(value)
+		case "duration":
+			return parseDurationValue(value)
+		case "timestamp":
+			return parseTimestamp(value)
+		case "cron":
+			return parseCron(value)
+		default:
+			// Look up every other format in the registry of custom formats.
+			fn, exists := lookupFormat(format)
+			if !exists {
+				return nil, fmt.Errorf("unknown format %q", format)
+			}
+			return fn(value)
 		}
 	default:
-		return errors.New("invalid format")
+		return nil, errors.New("invalid format")
 	}
 
-	// Return false if the value is not in the expected format.
-	return nil
+	// Return the value as it is if it is in the expected format.
+	return value, nil
+}
+
+// schemaProperties lists the reserved properties a schema leaf may declare.
+var schemaProperties = []string{
+	"env", "default", "format", "doc", "required", "nullable", "items",
+	"min", "max", "multipleOf", "minLength", "maxLength", "pattern",
 }
 
-// isNestedMap checks if a map is a nested map or not.
-func isNestedMap(config map[string]interface{}) bool {
+// isSchemaLeaf checks if a map is a schema leaf, which either declares only schema properties such as "format" or "doc",
+// or declares a "default" or an "env" together with other plain values. Every other map is a namespace of nested keys,
+// so that namespaces may have keys named like schema properties, e.g. "format" next to "level" or "min" and "max" holding leaves.
+func isSchemaLeaf(config map[string]interface{}) bool {
+	hasProperty, onlyProperties, hasValue, hasNestedKey := false, true, false, false
+
 	// Iterate over all keys in the configuration data.
-	for key := range config {
-		// Check if this map contains another map.
-		if _, ok := config[key].(map[string]interface{}); ok {
-			return true
+	for key, value := range config {
+		// Check if this key is a reserved schema property holding a value of its type.
+		if isSchemaProperty(key, value) {
+			hasProperty = true
+			hasValue = hasValue || key == "default" || key == "env"
+			continue
+		}
+		onlyProperties = false
+		if _, isMap := value.(map[string]interface{}); isMap {
+			hasNestedKey = true
 		}
 	}
-	// Return false if no nested key is found.
-	return false
+
+	// Return true if the map only declares properties, or declares a value without nesting other keys.
+	return hasProperty && (onlyProperties || (hasValue && !hasNestedKey))
+}
+
+// isSchemaProperty checks if a key and its value declare a schema property. Defaults may hold any value and items hold a schema,
+// the other properties hold scalars or, for formats, lists of values, so an object under their name is a nested key instead.
+func isSchemaProperty(key string, value interface{}) bool {
+	if !contains(schemaProperties, key) {
+		return false
+	}
+	_, isMap := value.(map[string]interface{})
+	switch key {
+	case "default":
+		return true
+	case "items":
+		return isMap
+	default:
+		return !isMap
+	}
+}
+
+// processState collects the results of processing the configuration data.
+type processState struct {
+	// origins holds the source of every key set by the value sources, by dotted path.
+	origins map[string]Source
+	// sources holds the source of every resolved value, by dotted path.
+	sources map[string]Source
+	// errors collects the validation errors of every key.
+	errors ValidationErrors
 }
 
 // processRecursively processes the configuration data recursively to resolve any nested maps and validate the data against the expected formats.
+// Every key that fails validation is collected and returned together as ValidationErrors.
 func (c *Config) processRecursively(config map[string]interface{}) (map[string]interface{}, error) {
+	current := c.current()
+	processedConfig, _, err := c.resolve(config, current.values, current.valueOrigins)
+	return processedConfig, err
+}
+
+// resolve processes the schema with the values merged over its defaults and returns the resolved data
+// together with the source of every resolved value.
+func (c *Config) resolve(config map[string]interface{}, values map[string]interface{}, origins map[string]Source) (map[string]interface{}, map[string]Source, error) {
+	// Collect the validation errors of every key instead of stopping at the first one.
+	state := &processState{origins: origins, sources: make(map[string]Source)}
+	processedConfig := c.processMap("", config, values, state)
+	if len(state.errors) > 0 {
+		// Sort the errors by key so that the report is stable.
+		sort.SliceStable(state.errors, func(i, j int) bool {
+			return state.errors[i].Key < state.errors[j].Key
+		})
+		return nil, nil, state.errors
+	}
+	return processedConfig, state.sources, nil
+}
+
+// origin returns the value source of the key at the given path, or the source of the closest parent that has one,
+// so that the keys inside values set as a whole, such as the items of an array, share the source of that value.
+func (s *processState) origin(path string) Source {
+	for current := path; current != ""; {
+		if source, exists := s.origins[current]; exists {
+			return source
+		}
+		if source, exists := s.sources[current]; exists && current != path {
+			return source
+		}
+		index := strings.LastIndexAny(current, ".[")
+		if index < 0 {
+			break
+		}
+		current = current[:index]
+	}
+	return Source{}
+}
+
+// processMap processes a single level of the configuration data found at the given path and records any validation errors.
+// The values map holds the keys of the value sources found at the same path, which override the defaults of the schema.
+func (c *Config) processMap(path string, config map[string]interface{}, values map[string]interface{}, state *processState) map[string]interface{} {
 	// Create a new map to store the processed configuration data.
 	processedConfig := make(map[string]interface{})
 
 	// Iterate over all keys in the configuration data.
 	for key, value := range config {
+		// Build the full dotted path of the key for the validation report.
+		keyPath := joinPath(path, key)
+
+		// Check if a value source sets the key.
+		layered, hasLayered := values[key]
+
 		// Check if the value is a nested map.
 		switch typedValue := value.(type) {
 		case map[string]interface{}:
-			// Check if the map is a nested map or not.
-			if !isNestedMap(typedValue) {
-				// If the map is not a nested map, validate and set the value using the validateAndSetValue method.
-				var err error
-				processedConfig[key], err = validateAndSetValue(typedValue)
+			// Check if the map is a schema leaf or a namespace of nested keys.
+			if isSchemaLeaf(typedValue) {
+				// If the map is a schema leaf, validate and set the value using the validateAndSetValue method.
+				var override *layerValue
+				if hasLayered {
+					override = &layerValue{value: layered, source: state.origin(keyPath)}
+				}
+				processedValue, source, err := c.validateAndSetValue(typedValue, override)
+				if source.Kind == SourceDefault {
+					// Point defaults at their location in the schema file.
+					source = c.defaultSource(keyPath)
+				}
 				if err != nil {
-					return nil, err
+					validationError := newValidationError(keyPath, err)
+					validationError.Source = source
+					state.errors = append(state.errors, validationError)
+					continue
 				}
+				state.sources[keyPath] = source
+
+				// Validate every item of arrays that declare a schema for their items.
+				if items, hasItems := typedValue["items"]; hasItems && processedValue != nil {
+					processedValue = c.processItems(keyPath, items, processedValue, source, state)
+				}
+				processedConfig[key] = processedValue
 			} else {
-				// If the map is a nested map, recursively process the nested map using the processRecursively method.
-				nestedConfig, err := c.processRecursively(typedValue)
-				if err != nil {
-					return nil, err
+				// A value source can only set the keys of a namespace, not replace it.
+				nestedValues, ok := layered.(map[string]interface{})
+				if hasLayered && !ok {
+					state.errors = append(state.errors, &ValidationError{
+						Key: keyPath, Value: layered, Source: state.origin(keyPath), Rule: "type",
+						Err: fmt.Errorf("expected an object of nested keys, got %T", layered),
+					})
+					continue
 				}
-				processedConfig[key] = nestedConfig
+				// If the map is a namespace, recursively process the nested map using the processMap method.
+				processedConfig[key] = c.processMap(keyPath, typedValue, nestedValues, state)
 			}
 		case []interface{}:
+			// A slice from a value source replaces the slice of the schema.
+			source := c.defaultSource(keyPath)
+			if hasLayered {
+				source = state.origin(keyPath)
+				layeredSlice, ok := layered.([]interface{})
+				if !ok {
+					state.errors = append(state.errors, &ValidationError{
+						Key: keyPath, Value: layered, Source: source, Rule: "type",
+						Err: fmt.Errorf("expected an array, got %T", layered),
+					})
+					continue
+				}
+				typedValue = layeredSlice
+			}
+
 			// If the value is a slice, process each item in the slice recursively.
 			processedSlice := make([]interface{}, len(typedValue))
 			for index, item := range typedValue {
 				switch nestedItem := item.(type) {
 				case map[string]interface{}:
-					// If an item is a nested map, recursively process the nested map using the processRecursively method.
-					processedItem, err := c.processRecursively(nestedItem)
-					if err != nil {
-						return nil, err
-					}
-					processedSlice[index] = processedItem
+					// If an item is a nested map, recursively process the nested map using the processMap method.
+					processedSlice[index] = c.processMap(fmt.Sprintf("%s[%d]", keyPath, index), nestedItem, nil, state)
 				default:
 					// If an item is not a nested map, add it to the processed slice as is.
 					processedSlice[index] = nestedItem
 				}
 			}
 			processedConfig[key] = processedSlice
+			state.sources[keyPath] = source
 		default:
-			// If the value is not a nested map or a slice, add it to the processed map as is
+			// If the value is not a nested map or a slice, add it to the processed map as is, unless a value source overrides it.
+			source := c.defaultSource(keyPath)
+			if hasLayered {
+				value, source = layered, state.origin(keyPath)
+			}
+			processedConfig[key] = value
+			state.sources[keyPath] = source
+		}
+	}
+
+	// Keep the keys of the value sources that the schema does not declare, unless the unknown key mode rejects them.
+	for key, value := range values {
+		if _, exists := config[key]; !exists && c.checkUnknownKey(path, key, value, config, state) {
 			processedConfig[key] = value
+			state.sources[joinPath(path, key)] = state.origin(joinPath(path, key))
+		}
+	}
+	return processedConfig
+}
+
+// processItems validates every item of an array against the "items" schema of its key and returns the converted items.
+// Scalar items are checked like the value of a leaf, object items are processed like a namespace whose keys they set.
+func (c *Config) processItems(path string, items interface{}, value interface{}, source Source, state *processState) interface{} {
+	// The items schema only applies to arrays.
+	itemsSchema, ok := items.(map[string]interface{})
+	if !ok {
+		state.errors = append(state.errors, &ValidationError{
+			Key: path, Value: items, Source: source, Rule: "schema",
+			Err: fmt.Errorf(`"items" must be a schema object, got %T`, items),
+		})
+		return value
+	}
+	slice, ok := value.([]interface{})
+	if !ok {
+		state.errors = append(state.errors, &ValidationError{
+			Key: path, Value: value, Source: source, Rule: "type",
+			Err: fmt.Errorf("expected an array, got %T", value),
+		})
+		return value
+	}
+
+	// Process every item below its indexed path.
+	processedSlice := make([]interface{}, len(slice))
+	for index, item := range slice {
+		itemPath := fmt.Sprintf("%s[%d]", path, index)
+		if isSchemaLeaf(itemsSchema) {
+			// Scalar items are validated against the items schema like a value source setting the key.
+			processedItem, _, err := c.validateAndSetValue(itemsSchema, &layerValue{value: item, source: source})
+			if err != nil {
+				validationError := newValidationError(itemPath, err)
+				validationError.Source = source
+				state.errors = append(state.errors, validationError)
+				continue
+			}
+
+			// Items may be arrays with an items schema of their own.
+			if nestedItems, hasItems := itemsSchema["items"]; hasItems && processedItem != nil {
+				processedItem = c.processItems(itemPath, nestedItems, processedItem, source, state)
+			}
+			processedSlice[index] = processedItem
+			continue
 		}
+
+		// Object items set the keys of the namespace declared by the items schema.
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			state.errors = append(state.errors, &ValidationError{
+				Key: itemPath, Value: item, Source: source, Rule: "type",
+				Err: fmt.Errorf("expected an object of nested keys, got %T", item),
+			})
+			continue
+		}
+		processedSlice[index] = c.processMap(itemPath, itemsSchema, itemMap, state)
+	}
+	return processedSlice
+}
+
+// joinPath appends a key to a dotted path.
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// newValidationError attaches the key path to an error returned while validating a value.
+func newValidationError(keyPath string, err error) *ValidationError {
+	// Reuse the details of a ValidationError, otherwise wrap the plain error.
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) {
+		validationError = &ValidationError{Rule: "format", Err: err}
 	}
-	return processedConfig, nil
+	validationError.Key = keyPath
+	return validationError
 }
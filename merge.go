@@ -0,0 +1,302 @@
+package configmaster
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// SliceStrategy decides how a slice is combined with the slice it is merged over.
+type SliceStrategy int
+
+const (
+	// SliceReplace replaces the existing slice with the merged one.
+	SliceReplace SliceStrategy = iota
+	// SliceAppend appends the items of the merged slice to the existing slice.
+	SliceAppend
+	// SliceMergeByIndex merges the items found at the same index, recursing into maps,
+	// and appends the items beyond the end of the existing slice.
+	SliceMergeByIndex
+)
+
+// Merge deep-merges the resolved values of another configuration over this one and validates the result against this configuration's schema.
+//
+// Nested maps are merged key by key, slices are replaced unless WithSliceStrategy selected another strategy for their key
+// and nil values never replace an existing value. Environment variables still take precedence over the merged values.
+// The configuration is left unchanged if the merged values do not validate.
+func (c *Config) Merge(other *Config) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.frozen {
+		return ErrFrozen
+	}
+	if other == nil {
+		return errors.New("[Config-Master]: cannot merge a nil configuration")
+	}
+
+	// Start from the values layered over the schema of this configuration and remember where they came from.
+	values, origins := c.currentValues()
+	current := c.current()
+
+	// Merge the resolved values of the other configuration over them, keeping the sources recorded by the other configuration.
+	// Its defaults are values of this configuration, traced to the file they were loaded from.
+	merged := other.current()
+	sourceOf := func(path string) Source {
+		source, exists := merged.sources[path]
+		switch {
+		case !exists:
+			return Source{Kind: SourceMerge}
+		case source.Kind == SourceDefault && source.Name != "":
+			return Source{Kind: SourceFile, Name: source.Name, Line: source.Line}
+		case source.Kind == SourceDefault:
+			return Source{Kind: SourceMerge}
+		default:
+			return source
+		}
+	}
+	overlay := prepareMerge("", values, merged.data, current.data, c.schema, current.sources, origins)
+	c.mergeValues("", values, overlay, sourceOf, origins)
+
+	// Resolve the schema again so that every merged value is validated.
+	return c.apply(values, origins)
+}
+
+// prepareMerge makes the values layered over the schema ready to have the overlay merged over them like the resolved values.
+// Leaves with object or array values and slices that only resolved from the schema are copied into the values, so that nested keys
+// are merged and slice strategies combine items with them, and the returned copy of the overlay drops the nil values of keys that
+// have a resolved value, as nil values never replace an existing value.
+func prepareMerge(path string, values map[string]interface{}, overlay map[string]interface{}, resolved map[string]interface{}, schema map[string]interface{}, sources map[string]Source, origins map[string]Source) map[string]interface{} {
+	prepared := make(map[string]interface{}, len(overlay))
+	for key, value := range overlay {
+		keyPath := joinPath(path, key)
+		resolvedValue, isResolved := resolved[key]
+		_, isLayered := values[key]
+		schemaMap, isNamespace := schema[key].(map[string]interface{})
+		isNamespace = isNamespace && !isSchemaLeaf(schemaMap)
+
+		switch overlayValue := value.(type) {
+		case nil:
+			if isResolved {
+				continue
+			}
+		case map[string]interface{}:
+			// Prepare the keys of namespaces one by one.
+			resolvedMap, isMap := resolvedValue.(map[string]interface{})
+			if isNamespace && isMap {
+				nestedValues, ok := values[key].(map[string]interface{})
+				if !isLayered {
+					nestedValues, ok = make(map[string]interface{}), true
+					values[key] = nestedValues
+				}
+				if ok {
+					value = prepareMerge(keyPath, nestedValues, overlayValue, resolvedMap, schemaMap, sources, origins)
+				}
+				break
+			}
+			if isMap && !isLayered {
+				seedValue(values, key, keyPath, resolvedValue, sources, origins)
+			}
+		case []interface{}:
+			// Slices are combined with the resolved slice.
+			if _, isSlice := resolvedValue.([]interface{}); isSlice && !isLayered {
+				seedValue(values, key, keyPath, resolvedValue, sources, origins)
+			}
+		}
+		prepared[key] = value
+	}
+	return prepared
+}
+
+// seedValue copies a resolved value into the layered values together with its source.
+func seedValue(values map[string]interface{}, key string, keyPath string, value interface{}, sources map[string]Source, origins map[string]Source) {
+	values[key] = copyValue(value)
+	if source, exists := sources[keyPath]; exists {
+		origins[keyPath] = source
+	}
+}
+
+// currentValues returns a copy of the values layered over the schema together with their sources, to be changed and applied again.
+// The values are taken before they were converted by their format, so that every format is only ever given its own input.
+func (c *Config) currentValues() (map[string]interface{}, map[string]Source) {
+	current := c.current()
+	origins := make(map[string]Source, len(current.valueOrigins))
+	for path, source := range current.valueOrigins {
+		origins[path] = source
+	}
+	return copyMap(current.values), origins
+}
+
+// apply resolves the schema with the given values merged over its defaults and replaces the resolved data on success.
+// The configuration is left unchanged if the values do not validate. The caller must hold the lock of the configuration.
+func (c *Config) apply(values map[string]interface{}, origins map[string]Source) error {
+	data, sources, err := c.resolve(c.schema, values, origins)
+	if err != nil {
+		return fmt.Errorf("[Config-Master]: %w", err)
+	}
+	c.state.Store(&snapshot{data: data, sources: sources, values: values, valueOrigins: origins})
+	return nil
+}
+
+// MergeSources creates a configuration from the first source and merges the values of the other sources over it in order.
+// Every source is a file path or a map like the input of NewConfig. The first source is loaded with the options and provides
+// the schema, the other sources hold plain values like the value sources of WithValues and are merged like Merge merges
+// a configuration, after the value sources of the options. The merged values are validated against the schema of the first source.
+func MergeSources(sources []interface{}, opts ...Option) (*Config, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("[Config-Master]: no sources to merge")
+	}
+
+	// Load the first source, which provides the schema.
+	cfg, err := NewConfig(sources[0], opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge the values of every other source over the values layered over the schema.
+	values, origins := cfg.currentValues()
+	current := cfg.current()
+	for _, source := range sources[1:] {
+		layer, err := cfg.parseInput(source)
+		if err != nil {
+			return nil, fmt.Errorf("[Config-Master]: error loading values: %w", err)
+		}
+
+		// Keys of files are traced to their line, keys of maps to the merge.
+		sourceOf := func(string) Source { return Source{Kind: SourceMerge} }
+		if filename, ok := source.(string); ok {
+			sourceOf = func(path string) Source {
+				return Source{Kind: SourceFile, Name: filename, Line: cfg.fileLines[filename][path]}
+			}
+		}
+		overlay := prepareMerge("", values, layer, current.data, cfg.schema, current.sources, origins)
+		cfg.mergeValues("", values, overlay, sourceOf, origins)
+	}
+
+	// Resolve the schema again so that every merged value is validated.
+	if err := cfg.apply(values, origins); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// mergeValues merges the overlay map into the base map, recursing into nested maps and combining slices by their strategy.
+// Nil values only set keys that do not exist yet. The source of every key set by the overlay is recorded in origins by its dotted path.
+func (c *Config) mergeValues(path string, base map[string]interface{}, overlay map[string]interface{}, sourceOf func(string) Source, origins map[string]Source) {
+	for key, value := range overlay {
+		keyPath := joinPath(path, key)
+
+		// Nil values never replace an existing value.
+		if value == nil {
+			if _, exists := base[key]; !exists {
+				base[key] = nil
+				origins[keyPath] = sourceOf(keyPath)
+			}
+			continue
+		}
+		origins[keyPath] = sourceOf(keyPath)
+
+		switch overlayValue := value.(type) {
+		case map[string]interface{}:
+			// Merge nested maps key by key, copying them so that the overlay is never modified.
+			baseMap, ok := base[key].(map[string]interface{})
+			if !ok {
+				baseMap = make(map[string]interface{}, len(overlayValue))
+				base[key] = baseMap
+			}
+			c.mergeValues(keyPath, baseMap, overlayValue, sourceOf, origins)
+		case []interface{}:
+			// Combine slices according to the strategy selected for the key.
+			base[key] = c.mergeSlices(keyPath, base[key], overlayValue, sourceOf, origins)
+		default:
+			// Every other value replaces the base value.
+			base[key] = value
+		}
+	}
+}
+
+// mergeSlices combines the overlay slice with the base value according to the strategy selected for the key.
+func (c *Config) mergeSlices(path string, base interface{}, overlay []interface{}, sourceOf func(string) Source, origins map[string]Source) []interface{} {
+	baseSlice, _ := base.([]interface{})
+
+	switch c.sliceStrategies[path] {
+	case SliceAppend:
+		// Append copies of the overlay items to a copy of the base slice.
+		merged := make([]interface{}, 0, len(baseSlice)+len(overlay))
+		for _, item := range baseSlice {
+			merged = append(merged, copyValue(item))
+		}
+		for _, item := range overlay {
+			merged = append(merged, copyValue(item))
+		}
+		return merged
+	case SliceMergeByIndex:
+		// Merge the items found at the same index and append the rest.
+		merged := make([]interface{}, 0, max(len(baseSlice), len(overlay)))
+		for _, item := range baseSlice {
+			merged = append(merged, copyValue(item))
+		}
+		for index, item := range overlay {
+			switch {
+			case index >= len(merged):
+				merged = append(merged, copyValue(item))
+			case item == nil:
+				// Nil items never replace an existing item.
+			default:
+				baseMap, baseIsMap := merged[index].(map[string]interface{})
+				overlayMap, overlayIsMap := item.(map[string]interface{})
+				if baseIsMap && overlayIsMap {
+					c.mergeValues(fmt.Sprintf("%s[%d]", path, index), baseMap, overlayMap, sourceOf, origins)
+					continue
+				}
+				merged[index] = copyValue(item)
+			}
+		}
+		return merged
+	default:
+		// Replace the base slice with a copy of the overlay.
+		merged := make([]interface{}, len(overlay))
+		for index, item := range overlay {
+			merged[index] = copyValue(item)
+		}
+		return merged
+	}
+}
+
+// copyMap returns a deep copy of a map of configuration data.
+func copyMap(value map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(value))
+	for key, nestedValue := range value {
+		copied[key] = copyValue(nestedValue)
+	}
+	return copied
+}
+
+// copyValue returns a deep copy of the maps and slices of a configuration value and of the IP addresses and URLs
+// produced by the formats, other values are returned as they are.
+func copyValue(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		return copyMap(typedValue)
+	case []interface{}:
+		copied := make([]interface{}, len(typedValue))
+		for index, item := range typedValue {
+			copied[index] = copyValue(item)
+		}
+		return copied
+	case net.IP:
+		return append(net.IP(nil), typedValue...)
+	case *url.URL:
+		if typedValue == nil {
+			return typedValue
+		}
+		copied := *typedValue
+		if typedValue.User != nil {
+			user := *typedValue.User
+			copied.User = &user
+		}
+		return &copied
+	default:
+		return value
+	}
+}
@@ -0,0 +1,318 @@
+package configmaster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// editFunc changes the value found at a path and reports whether the key is kept, returning false removes it.
+type editFunc func(value interface{}, exists bool) (interface{}, bool, error)
+
+// Has reports whether a value exists at the key path, including null values.
+func (c *Config) Has(key string) bool {
+	segments, err := parsePath(key)
+	if err != nil {
+		return false
+	}
+	_, err = lookupSegments(key, c.current().data, segments)
+	return err == nil
+}
+
+// Set sets the value at the key path, creating intermediate maps as needed, and validates the result against the schema.
+//
+// The value is checked against the format and the constraints of its key, values set with Set take precedence over
+// environment variables and are explained as "set". The configuration is left unchanged if the value does not validate.
+func (c *Config) Set(key string, value interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.frozen {
+		return ErrFrozen
+	}
+
+	segments, err := parsePath(key)
+	if err != nil {
+		return &PathError{Path: key, Err: err}
+	}
+
+	// Set the value in a copy of the values layered over the schema, keeping the arrays of the schema and the value of a leaf
+	// that the key is inside of.
+	values, origins := c.currentValues()
+	if err := c.seedSlice(key, values, origins, segments); err != nil {
+		return err
+	}
+	leafPath, err := c.seedLeaf(key, values, origins, segments)
+	if err != nil {
+		return err
+	}
+	value = normalizeValue(copyValue(value))
+	_, path, err := editPath(key, values, segments, "", true, func(interface{}, bool) (interface{}, bool, error) {
+		return value, true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Forget where the replaced value came from and remember that it was set.
+	if leafPath == "" {
+		clearOrigins(origins, path)
+		origins[path] = Source{Kind: SourceSet}
+	}
+
+	// Resolve the schema again so that the value is validated.
+	return c.apply(values, origins)
+}
+
+// Delete removes the value set at the key path by Set, Merge or the value sources and resolves the schema again,
+// so that keys declared by the schema fall back to their environment variable or their default, which are kept as they are.
+// The configuration is left unchanged if the remaining values do not validate, e.g. when a required key has no other value.
+func (c *Config) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.frozen {
+		return ErrFrozen
+	}
+
+	segments, err := parsePath(key)
+	if err != nil {
+		return &PathError{Path: key, Err: err}
+	}
+
+	// Keys that only resolve to their environment variable or their default have nothing to remove.
+	values, origins := c.currentValues()
+	if _, err := lookupSegments(key, values, segments); err != nil {
+		if _, resolveErr := c.Lookup(key); resolveErr != nil {
+			return resolveErr
+		}
+		if depth := c.leafDepth(segments); depth == 0 || depth == len(segments) {
+			return nil
+		}
+	}
+
+	// Remove the value from a copy of the values layered over the schema, keeping the rest of the arrays of the schema
+	// and of a leaf that the key is inside of.
+	if err := c.seedSlice(key, values, origins, segments); err != nil {
+		return err
+	}
+	leafPath, err := c.seedLeaf(key, values, origins, segments)
+	if err != nil {
+		return err
+	}
+	_, path, err := editPath(key, values, segments, "", false, func(_ interface{}, exists bool) (interface{}, bool, error) {
+		if !exists {
+			return nil, false, ErrKeyNotFound
+		}
+		return nil, false, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Forget where the removed value came from, the items after a removed item move to other indexes.
+	switch {
+	case leafPath != "":
+	case strings.HasSuffix(path, "]"):
+		path = path[:strings.LastIndex(path, "[")]
+		for origin := range origins {
+			if strings.HasPrefix(origin, path+"[") {
+				delete(origins, origin)
+			}
+		}
+	default:
+		clearOrigins(origins, path)
+	}
+
+	// Resolve the schema again so that the keys fall back to their environment variable or their default.
+	return c.apply(values, origins)
+}
+
+// seedLeaf copies the current value of the schema leaf that the key path is inside of, such as an array with an items schema,
+// into the layered values, so that changing a part of the leaf keeps the rest of its value. The whole leaf is then marked as set.
+// It returns the path of the leaf, or an empty path when the key path is not inside of a leaf.
+func (c *Config) seedLeaf(key string, values map[string]interface{}, origins map[string]Source, segments []pathSegment) (string, error) {
+	depth := c.leafDepth(segments)
+	if depth == 0 || depth == len(segments) {
+		return "", nil
+	}
+
+	// Keep a value that is already layered, otherwise take the resolved value of the leaf.
+	resolved, err := lookupSegments(key, c.current().data, segments[:depth])
+	if err != nil {
+		return "", err
+	}
+	_, leafPath, err := editPath(key, values, segments[:depth], "", true, func(value interface{}, exists bool) (interface{}, bool, error) {
+		if exists && value != nil {
+			return value, true, nil
+		}
+		return copyValue(resolved), true, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	clearOrigins(origins, leafPath)
+	origins[leafPath] = Source{Kind: SourceSet}
+	return leafPath, nil
+}
+
+// seedSlice copies the current value of the first schema array that the key path goes through, such as an array of namespaces,
+// into the layered values together with its source, so that changing one of its items keeps it an array with its other items.
+func (c *Config) seedSlice(key string, values map[string]interface{}, origins map[string]Source, segments []pathSegment) error {
+	var node interface{} = c.schema
+	for depth, segment := range segments[:len(segments)-1] {
+		next, isLeaf, ok := schemaChild(node, segment)
+		if !ok || isLeaf {
+			return nil
+		}
+		node = next
+		if _, isSlice := node.([]interface{}); !isSlice {
+			continue
+		}
+
+		// Keep a value that is already layered, otherwise take the resolved array.
+		if _, err := lookupSegments(key, values, segments[:depth+1]); err == nil {
+			return nil
+		}
+		current := c.current()
+		resolved, err := lookupSegments(key, current.data, segments[:depth+1])
+		if err != nil {
+			return err
+		}
+		_, slicePath, err := editPath(key, values, segments[:depth+1], "", true, func(interface{}, bool) (interface{}, bool, error) {
+			return copyValue(resolved), true, nil
+		})
+		if err != nil {
+			return err
+		}
+		if source, exists := current.sources[slicePath]; exists {
+			origins[slicePath] = source
+		}
+		return nil
+	}
+	return nil
+}
+
+// leafDepth returns the number of segments of the key path that lead to a schema leaf, or 0 when the path does not reach one.
+func (c *Config) leafDepth(segments []pathSegment) int {
+	var node interface{} = c.schema
+	for depth, segment := range segments {
+		next, isLeaf, ok := schemaChild(node, segment)
+		if !ok {
+			return 0
+		}
+		if isLeaf {
+			return depth + 1
+		}
+		node = next
+	}
+	return 0
+}
+
+// schemaChild returns the schema node found at a segment below a namespace or an array of the schema
+// and reports whether it is a schema leaf.
+func schemaChild(node interface{}, segment pathSegment) (interface{}, bool, bool) {
+	switch current := node.(type) {
+	case map[string]interface{}:
+		name := segment.key
+		if segment.isIndex {
+			name = strconv.Itoa(segment.index)
+		}
+		next, exists := current[name]
+		if !exists {
+			return nil, false, false
+		}
+		nested, isMap := next.(map[string]interface{})
+		return next, isMap && isSchemaLeaf(nested), true
+	case []interface{}:
+		index, ok := segmentIndex(segment)
+		if !ok || index >= len(current) {
+			return nil, false, false
+		}
+		return current[index], false, true
+	default:
+		return nil, false, false
+	}
+}
+
+// editPath applies edit to the value found at the segments below the container and returns the changed container
+// together with the path of the value in the form used by validation reports.
+// Missing intermediate maps are created when create is set, otherwise they are reported like Lookup does.
+func editPath(key string, container interface{}, segments []pathSegment, traversed string, create bool, edit editFunc) (interface{}, string, error) {
+	segment, last := segments[0], len(segments) == 1
+
+	switch current := container.(type) {
+	case map[string]interface{}:
+		// Objects are edited by key, even when the key looks like an index.
+		name := segment.key
+		if segment.isIndex {
+			name = strconv.Itoa(segment.index)
+		}
+		at := joinPath(traversed, name)
+		next, exists := current[name]
+		if last {
+			value, keep, err := edit(next, exists)
+			if err != nil {
+				return nil, at, &PathError{Path: key, At: at, Err: err}
+			}
+			if keep {
+				current[name] = value
+			} else {
+				delete(current, name)
+			}
+			return current, at, nil
+		}
+
+		// Create the intermediate maps that do not exist yet.
+		if !exists || next == nil {
+			if !create {
+				return nil, at, &PathError{Path: key, At: at, Err: ErrKeyNotFound}
+			}
+			next = make(map[string]interface{})
+		}
+		child, path, err := editPath(key, next, segments[1:], at, create, edit)
+		if err != nil {
+			return nil, path, err
+		}
+		current[name] = child
+		return current, path, nil
+	case []interface{}:
+		// Arrays are edited by index, given in brackets or as a numeric segment.
+		index, ok := segmentIndex(segment)
+		if !ok {
+			return nil, traversed, &PathError{Path: key, At: joinPath(traversed, segment.key), Err: fmt.Errorf("cannot set key %q in an array", segment.key)}
+		}
+		at := fmt.Sprintf("%s[%d]", traversed, index)
+		if index >= len(current) {
+			return nil, at, &PathError{Path: key, At: at, Err: fmt.Errorf("%w: index %d out of range for array of length %d", ErrKeyNotFound, index, len(current))}
+		}
+		if last {
+			value, keep, err := edit(current[index], true)
+			if err != nil {
+				return nil, at, &PathError{Path: key, At: at, Err: err}
+			}
+			if !keep {
+				// Removing an item moves the items after it.
+				return append(current[:index:index], current[index+1:]...), at, nil
+			}
+			current[index] = value
+			return current, at, nil
+		}
+		child, path, err := editPath(key, current[index], segments[1:], at, create, edit)
+		if err != nil {
+			return nil, path, err
+		}
+		current[index] = child
+		return current, path, nil
+	default:
+		at := appendPathSegment(traversed, segment)
+		return nil, at, &PathError{Path: key, At: at, Err: fmt.Errorf("cannot traverse %T value at %s", container, traversed)}
+	}
+}
+
+// clearOrigins removes the sources recorded for a path and for every key below it.
+func clearOrigins(origins map[string]Source, path string) {
+	for origin := range origins {
+		if origin == path || strings.HasPrefix(origin, path+".") || strings.HasPrefix(origin, path+"[") {
+			delete(origins, origin)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package configmaster
+
+import (
+	"log"
+	"time"
+)
+
+// Option configures how NewConfig loads and resolves the configuration.
+type Option func(*Config)
+
+// FileFormat identifies the syntax of a configuration file.
+type FileFormat string
+
+const (
+	// FormatJSON parses files as JSON.
+	FormatJSON FileFormat = "json"
+	// FormatYAML parses files as YAML.
+	FormatYAML FileFormat = "yaml"
+	// FormatTOML parses files as TOML.
+	FormatTOML FileFormat = "toml"
+	// FormatJSON5 parses files as JSON5, allowing comments, trailing commas, unquoted keys and single-quoted strings.
+	FormatJSON5 FileFormat = "json5"
+)
+
+// WithFileFormat forces the syntax used to parse configuration files instead of detecting it from the file extension.
+func WithFileFormat(format FileFormat) Option {
+	return func(c *Config) {
+		c.fileFormat = format
+	}
+}
+
+// WithJSON5 parses files with the .json extension as JSON5 so that they can contain comments.
+func WithJSON5() Option {
+	return func(c *Config) {
+		c.json5 = true
+	}
+}
+
+// WithEnvSource replaces the process environment as the source of the variables referenced by "env" properties.
+func WithEnvSource(source EnvSource) Option {
+	return func(c *Config) {
+		c.envSource = source
+	}
+}
+
+// WithDotEnv loads variables from one or more .env files and consults them when resolving "env" properties.
+// Later files override the variables of earlier ones, the process environment is never modified.
+func WithDotEnv(filenames ...string) Option {
+	return func(c *Config) {
+		c.dotEnvFiles = append(c.dotEnvFiles, filenames...)
+	}
+}
+
+// WithEnvPrecedence decides whether the environment source or the .env files win when both set a variable.
+// By default the environment source wins.
+func WithEnvPrecedence(precedence EnvPrecedence) Option {
+	return func(c *Config) {
+		c.envPrecedence = precedence
+	}
+}
+
+// WithValues merges one or more value sources over the defaults of the schema, in order, before the environment is applied.
+// Each source is a file path or a map, like the input of NewConfig, holding plain values for the keys of the schema,
+// and every value is validated against the format of its key.
+func WithValues(sources ...interface{}) Option {
+	return func(c *Config) {
+		c.valueSources = append(c.valueSources, sources...)
+	}
+}
+
+// WithSliceStrategy selects how the slice found at the dotted key is combined when value sources or configurations are merged.
+// Slices are replaced by default.
+func WithSliceStrategy(key string, strategy SliceStrategy) Option {
+	return func(c *Config) {
+		if c.sliceStrategies == nil {
+			c.sliceStrategies = make(map[string]SliceStrategy)
+		}
+		c.sliceStrategies[key] = strategy
+	}
+}
+
+// WithFormat registers a custom format for the configuration, the same as Config.RegisterFormat.
+func WithFormat(name string, fn FormatFunc) Option {
+	return func(c *Config) {
+		c.RegisterFormat(name, fn)
+	}
+}
+
+// WithStrictSchema checks the schema before any value is resolved and fails with every malformed leaf:
+// unsupported properties, non-string "env" values, unknown format names and defaults that do not match their format.
+func WithStrictSchema() Option {
+	return func(c *Config) {
+		c.strictSchema = true
+	}
+}
+
+// WithUnknownKeys selects how keys of value files and maps that the schema does not declare are handled.
+// UnknownKeysReject reports each of them with the closest declared key as a suggestion, UnknownKeysWarn logs them instead.
+func WithUnknownKeys(mode UnknownKeyMode) Option {
+	return func(c *Config) {
+		c.unknownKeys = mode
+	}
+}
+
+// WithLogger sets the logger used for warnings, which defaults to the standard logger.
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Config) {
+		c.logger = logger
+	}
+}
+
+// WithWatchPolling makes Watch poll the files at the given interval instead of using file system notifications,
+// e.g. for network file systems that do not deliver them.
+func WithWatchPolling(interval time.Duration) Option {
+	return func(c *Config) {
+		c.pollInterval = interval
+	}
+}
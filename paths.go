@@ -0,0 +1,223 @@
+package configmaster
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPath is wrapped by the errors of key paths that cannot be parsed.
+var ErrInvalidPath = errors.New("invalid path")
+
+// PathError is returned by Lookup when a key path cannot be parsed or traversed.
+type PathError struct {
+	// Path is the key path that was requested.
+	Path string
+	// At is the part of the path where traversal failed, e.g. "xyz[5]", or empty when the path cannot be parsed.
+	At string
+	// Err describes the failure and wraps ErrKeyNotFound for keys and indexes that do not exist.
+	Err error
+}
+
+// Error returns a description of the failure naming the path and where traversal failed.
+func (e *PathError) Error() string {
+	if e.At == "" {
+		return fmt.Sprintf("path %q: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("path %q: at %q: %v", e.Path, e.At, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// pathSegment is a single step of a key path, either a key of an object or an index of an array.
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// Lookup retrieves a value by its key path and reports why it cannot be found.
+//
+// Path segments are separated by dots and arrays are indexed with brackets or numeric segments, e.g. "xyz[1].bar" or "xyz.1.bar".
+// Keys that contain dots or brackets are escaped with a backslash, e.g. `a\.b`, or quoted in brackets, e.g. `a["b.c"]`.
+// Maps and slices are returned as copies, so changing them does not change the configuration or its snapshots.
+func (c *Config) Lookup(key string) (interface{}, error) {
+	segments, err := parsePath(key)
+	if err != nil {
+		return nil, &PathError{Path: key, Err: err}
+	}
+	value, err := lookupSegments(key, c.current().data, segments)
+	if err != nil {
+		return nil, err
+	}
+	return copyValue(value), nil
+}
+
+// lookupSegments traverses the value one segment at a time and returns the value found at the end of the path.
+func lookupSegments(key string, value interface{}, segments []pathSegment) (interface{}, error) {
+	traversed := ""
+	for _, segment := range segments {
+		at := appendPathSegment(traversed, segment)
+		switch current := value.(type) {
+		case map[string]interface{}:
+			// Objects are traversed by key, even when the key looks like an index.
+			name := segment.key
+			if segment.isIndex {
+				name = strconv.Itoa(segment.index)
+			}
+			next, exists := current[name]
+			if !exists {
+				return nil, &PathError{Path: key, At: at, Err: ErrKeyNotFound}
+			}
+			value = next
+		case []interface{}:
+			// Arrays are traversed by index, given in brackets or as a numeric segment.
+			index, ok := segmentIndex(segment)
+			if !ok {
+				return nil, &PathError{Path: key, At: at, Err: fmt.Errorf("cannot look up key %q in an array", segment.key)}
+			}
+			if index >= len(current) {
+				return nil, &PathError{Path: key, At: at, Err: fmt.Errorf("%w: index %d out of range for array of length %d", ErrKeyNotFound, index, len(current))}
+			}
+			value = current[index]
+		case nil:
+			return nil, &PathError{Path: key, At: at, Err: fmt.Errorf("%w: %s is null", ErrKeyNotFound, traversed)}
+		default:
+			return nil, &PathError{Path: key, At: at, Err: fmt.Errorf("cannot traverse %T value at %s", value, traversed)}
+		}
+		traversed = at
+	}
+	return value, nil
+}
+
+// segmentIndex returns the array index of a segment, given in brackets or as a numeric key.
+func segmentIndex(segment pathSegment) (int, bool) {
+	if segment.isIndex {
+		return segment.index, true
+	}
+	index, err := strconv.Atoi(segment.key)
+	if err != nil || index < 0 || strings.TrimLeft(segment.key, "0123456789") != "" {
+		return 0, false
+	}
+	return index, true
+}
+
+// parsePath splits a key path into its segments.
+func parsePath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	var key strings.Builder
+	hasKey, afterBracket := false, false
+
+	for pos := 0; pos < len(path); pos++ {
+		switch char := path[pos]; char {
+		case '\\':
+			// An escaped character is part of the key.
+			if pos+1 == len(path) {
+				return nil, fmt.Errorf("%w: trailing backslash at offset %d", ErrInvalidPath, pos)
+			}
+			pos++
+			key.WriteByte(path[pos])
+			hasKey, afterBracket = true, false
+		case '.':
+			// A dot ends the current key, or follows a bracket, and must be followed by another segment.
+			if !hasKey && !afterBracket {
+				return nil, fmt.Errorf("%w: empty key at offset %d", ErrInvalidPath, pos)
+			}
+			if pos+1 == len(path) {
+				return nil, fmt.Errorf("%w: empty key at offset %d", ErrInvalidPath, pos+1)
+			}
+			if hasKey {
+				segments = append(segments, pathSegment{key: key.String()})
+				key.Reset()
+			}
+			hasKey, afterBracket = false, false
+		case '[':
+			// A bracket ends the current key and holds an index or a quoted key.
+			if hasKey {
+				segments = append(segments, pathSegment{key: key.String()})
+				key.Reset()
+			} else if pos > 0 && !afterBracket {
+				return nil, fmt.Errorf("%w: empty key at offset %d", ErrInvalidPath, pos)
+			}
+			segment, end, err := parsePathBracket(path, pos)
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, segment)
+			pos = end
+			hasKey, afterBracket = false, true
+
+			// Only another segment may follow the closing bracket.
+			if pos+1 < len(path) && path[pos+1] != '.' && path[pos+1] != '[' {
+				return nil, fmt.Errorf("%w: expected '.' or '[' at offset %d", ErrInvalidPath, pos+1)
+			}
+		default:
+			key.WriteByte(char)
+			hasKey, afterBracket = true, false
+		}
+	}
+
+	// Add the last key.
+	if hasKey {
+		segments = append(segments, pathSegment{key: key.String()})
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("%w: empty path", ErrInvalidPath)
+	}
+	return segments, nil
+}
+
+// parsePathBracket parses the bracket starting at the given offset and returns its segment and the offset of the closing bracket.
+func parsePathBracket(path string, start int) (pathSegment, int, error) {
+	pos := start + 1
+
+	// Quoted keys may contain any character, quotes and backslashes are escaped with a backslash.
+	if pos < len(path) && (path[pos] == '"' || path[pos] == '\'') {
+		quote := path[pos]
+		var key strings.Builder
+		for pos++; pos < len(path); pos++ {
+			switch {
+			case path[pos] == '\\' && pos+1 < len(path):
+				pos++
+				key.WriteByte(path[pos])
+			case path[pos] == quote:
+				if pos+1 >= len(path) || path[pos+1] != ']' {
+					return pathSegment{}, 0, fmt.Errorf("%w: expected ']' at offset %d", ErrInvalidPath, pos+1)
+				}
+				return pathSegment{key: key.String()}, pos + 1, nil
+			default:
+				key.WriteByte(path[pos])
+			}
+		}
+		return pathSegment{}, 0, fmt.Errorf("%w: unterminated quoted key at offset %d", ErrInvalidPath, start+1)
+	}
+
+	// Everything else is an index made of digits.
+	end := strings.IndexByte(path[pos:], ']')
+	if end < 0 {
+		return pathSegment{}, 0, fmt.Errorf("%w: unterminated '[' at offset %d", ErrInvalidPath, start)
+	}
+	digits := path[pos : pos+end]
+	index, err := strconv.Atoi(digits)
+	if err != nil || digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return pathSegment{}, 0, fmt.Errorf("%w: invalid index %q at offset %d", ErrInvalidPath, digits, pos)
+	}
+	return pathSegment{index: index, isIndex: true}, pos + end, nil
+}
+
+// appendPathSegment appends a segment to a path in the form used by validation reports,
+// quoting keys that contain dots, brackets, quotes or backslashes.
+func appendPathSegment(path string, segment pathSegment) string {
+	switch {
+	case segment.isIndex:
+		return fmt.Sprintf("%s[%d]", path, segment.index)
+	case strings.ContainsAny(segment.key, `.[]"\`):
+		return fmt.Sprintf("%s[%q]", path, segment.key)
+	default:
+		return joinPath(path, segment.key)
+	}
+}
@@ -0,0 +1,114 @@
+package configmaster
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SourceKind identifies the kind of source a configuration value came from.
+type SourceKind string
+
+const (
+	// SourceDefault marks values taken from the default of the schema.
+	SourceDefault SourceKind = "default"
+	// SourceEnv marks values taken from an environment variable.
+	SourceEnv SourceKind = "env"
+	// SourceFile marks values taken from a file passed to WithValues.
+	SourceFile SourceKind = "file"
+	// SourceValues marks values taken from a map passed to WithValues.
+	SourceValues SourceKind = "values"
+	// SourceMerge marks values merged from another configuration that were defaults of a map or did not record their source.
+	SourceMerge SourceKind = "merge"
+	// SourceSet marks values set at runtime with Set, which take precedence over environment variables.
+	SourceSet SourceKind = "set"
+	// SourceSchema marks problems found in the schema itself by WithStrictSchema.
+	SourceSchema SourceKind = "schema"
+)
+
+// Source describes where a configuration value came from.
+type Source struct {
+	// Kind is the kind of the source.
+	Kind SourceKind
+	// Name is the environment variable, the file path or the value source, e.g. "PORT", "config.yaml" or "values[1]".
+	// Defaults are named by the schema file when the schema was loaded from one.
+	Name string
+	// Line is the 1-based line of the key in the file, or 0 when it is not known.
+	Line int
+}
+
+// String describes the source, e.g. "default", "env:PORT" or "file:config.yaml:12".
+func (s Source) String() string {
+	// Add the line to the name when it is known.
+	location := s.Name
+	if s.Line > 0 {
+		location = fmt.Sprintf("%s:%d", location, s.Line)
+	}
+
+	switch {
+	case location == "":
+		return string(s.Kind)
+	case s.Kind == SourceValues:
+		// Value maps are already named after their kind.
+		return location
+	default:
+		return string(s.Kind) + ":" + location
+	}
+}
+
+// Explain returns the source of the value found at the dotted key.
+// Keys inside a value that was set as a whole, such as an object default, are explained by the source of that value.
+func (c *Config) Explain(key string) (Source, bool) {
+	// Walk up the path until a key with a recorded source is found.
+	sources := c.current().sources
+	for path := key; path != ""; {
+		if source, exists := sources[path]; exists {
+			return source, true
+		}
+		index := strings.LastIndexAny(path, ".[")
+		if index < 0 {
+			break
+		}
+		path = path[:index]
+	}
+	return Source{}, false
+}
+
+// Provenance returns the source of every resolved value by dotted key, e.g. "qux.bar" or "xyz[1].bar".
+func (c *Config) Provenance() map[string]Source {
+	sources := c.current().sources
+	provenance := make(map[string]Source, len(sources))
+	for path, source := range sources {
+		provenance[path] = source
+	}
+	return provenance
+}
+
+// defaultSource returns the source of the default of the key, pointing at its line when the schema was loaded from a file.
+func (c *Config) defaultSource(keyPath string) Source {
+	source := Source{Kind: SourceDefault, Name: c.schemaFile}
+	if line, exists := c.fileLines[c.schemaFile][keyPath+".default"]; exists {
+		source.Line = line
+	} else {
+		source.Line = c.fileLines[c.schemaFile][keyPath]
+	}
+	return source
+}
+
+// recordLines remembers the line of every key of a parsed file by dotted path.
+func (c *Config) recordLines(filename string, format FileFormat, byteValue []byte) {
+	var lines map[string]int
+	switch format {
+	case FormatJSON, FormatJSON5:
+		// JSON is a subset of JSON5, so the JSON5 parser finds the keys of both.
+		lines = json5LineIndex(byteValue)
+	case FormatYAML:
+		lines = yamlLineIndex(byteValue)
+	case FormatTOML:
+		lines = tomlLineIndex(byteValue)
+	}
+
+	if c.fileLines == nil {
+		c.fileLines = make(map[string]map[string]int)
+	}
+	c.fileLines[filename] = lines
+}
@@ -0,0 +1,76 @@
+package configmaster
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// FormatFunc validates a value of a custom format and returns it converted into the type of the format.
+// Values of environment variables are passed as strings, values of files as decoded by their parser.
+type FormatFunc func(value interface{}) (interface{}, error)
+
+// builtinFormats lists the format names handled by isValueInExpectedFormat, which cannot be registered.
+var builtinFormats = []string{
+	"string", "bool", "float64", "int", "object", "array",
+	"port", "nat", "url", "email", "ipaddress", "ipv4", "ipv6", "hostname", "duration", "timestamp", "cron",
+}
+
+// formatRegistry holds the formats registered for every configuration.
+var formatRegistry = struct {
+	sync.RWMutex
+	formats map[string]FormatFunc
+}{formats: make(map[string]FormatFunc)}
+
+// RegisterFormat registers a custom format for every configuration, replacing a format registered with the same name.
+// Format names are case-insensitive. It panics if the name is empty or the name of a built-in format, or if fn is nil.
+func RegisterFormat(name string, fn FormatFunc) {
+	name = checkFormatRegistration(name, fn)
+	formatRegistry.Lock()
+	defer formatRegistry.Unlock()
+	formatRegistry.formats[name] = fn
+}
+
+// RegisterFormat registers a custom format for this configuration only, taking precedence over the formats registered with
+// the package-level RegisterFormat. It applies the next time the values are validated, use WithFormat to apply it in NewConfig.
+func (c *Config) RegisterFormat(name string, fn FormatFunc) {
+	name = checkFormatRegistration(name, fn)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.formats == nil {
+		c.formats = make(map[string]FormatFunc)
+	}
+	c.formats[name] = fn
+}
+
+// checkFormatRegistration panics if a format cannot be registered and returns its normalised name.
+func checkFormatRegistration(name string, fn FormatFunc) string {
+	name = strings.ToLower(name)
+	switch {
+	case name == "":
+		panic("configmaster: RegisterFormat called with an empty name")
+	case contains(builtinFormats, name):
+		panic(fmt.Sprintf("configmaster: RegisterFormat called with the built-in format %q", name))
+	case fn == nil:
+		panic(fmt.Sprintf("configmaster: RegisterFormat called with a nil function for format %q", name))
+	}
+	return name
+}
+
+// lookupFormat returns the custom format registered for every configuration under the name.
+func lookupFormat(name string) (FormatFunc, bool) {
+	formatRegistry.RLock()
+	defer formatRegistry.RUnlock()
+	fn, exists := formatRegistry.formats[strings.ToLower(name)]
+	return fn, exists
+}
+
+// formatValue checks a value against its format like isValueInExpectedFormat, looking up custom formats of this configuration first.
+func (c *Config) formatValue(value interface{}, format interface{}) (interface{}, error) {
+	if name, ok := format.(string); ok {
+		if fn, exists := c.formats[strings.ToLower(name)]; exists {
+			return fn(value)
+		}
+	}
+	return isValueInExpectedFormat(value, format)
+}
@@ -0,0 +1,68 @@
+package configmaster
+
+import (
+	"errors"
+	"maps"
+)
+
+// ErrFrozen is returned when a snapshot returned by Snapshot is changed.
+var ErrFrozen = errors.New("cannot change a frozen snapshot")
+
+// snapshot holds the resolved state of a configuration. It is never changed once it is stored,
+// changes store a new snapshot so that readers never see a partially updated configuration.
+type snapshot struct {
+	data         map[string]interface{}
+	sources      map[string]Source
+	values       map[string]interface{}
+	valueOrigins map[string]Source
+}
+
+// current returns the snapshot that readers of the configuration see.
+func (c *Config) current() *snapshot {
+	if current := c.state.Load(); current != nil {
+		return current
+	}
+	return &snapshot{}
+}
+
+// Snapshot returns a frozen copy of the configuration that keeps its current values while the configuration is changed or reloaded.
+// The snapshot can be read like the configuration, Set, Delete, Merge and Reload return ErrFrozen. It shares the resolved data,
+// which is never changed in place, as readers only ever get copies of its maps, slices, IP addresses and URLs.
+func (c *Config) Snapshot() *Config {
+	frozen := &Config{frozen: true}
+	frozen.state.Store(c.current())
+	return frozen
+}
+
+// Reload loads the input of NewConfig, its .env files and its value sources again with the same options and validates them.
+// Values changed with Set, Delete or Merge are discarded. The configuration is left unchanged if the reloaded values do not validate.
+func (c *Config) Reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.frozen {
+		return ErrFrozen
+	}
+
+	// Keep the formats registered on this configuration after it was created and the undeclared keys already warned about.
+	formats, warnedKeys := c.formats, c.warnedKeys
+	opts := append(append([]Option(nil), c.opts...), func(fresh *Config) {
+		for name, fn := range formats {
+			if fresh.formats == nil {
+				fresh.formats = make(map[string]FormatFunc)
+			}
+			fresh.formats[name] = fn
+		}
+		fresh.warnedKeys = maps.Clone(warnedKeys)
+	})
+
+	// Load everything again in a new configuration, so that a failure does not change this one.
+	fresh, err := NewConfig(c.input, opts...)
+	if err != nil {
+		return err
+	}
+
+	// Take over the reloaded schema and environment, then publish the reloaded values.
+	c.env, c.schema, c.fileLines, c.warnedKeys = fresh.env, fresh.schema, fresh.fileLines, fresh.warnedKeys
+	c.state.Store(fresh.current())
+	return nil
+}
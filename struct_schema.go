@@ -0,0 +1,221 @@
+package configmaster
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// timeType is the reflected type of time.Time, which is a leaf value rather than a nested struct.
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaFromStruct builds the schema map consumed by NewConfig from the tags of a struct or a pointer to one.
+//
+// Every exported field becomes a key named by its `config:"name"` tag, or by the field name when untagged.
+// Nested structs become nested keys and embedded structs without a name share the keys of their parent, recursive struct types are rejected.
+// Other fields become leaves described by the tags `env:"PORT"`, `default:"8080"`, `format:"int"` and `doc:"..."`.
+// The format is inferred from the field type for strings, bools, integers and floats when it is not set,
+// and fields without a default tag default to the zero value of their type unless they are tagged `required:"true"`.
+func SchemaFromStruct(v interface{}) (map[string]interface{}, error) {
+	// Dereference pointers to find the struct type.
+	structType := reflect.TypeOf(v)
+	for structType != nil && structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType == nil || structType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema source must be a struct or a pointer to a struct, got %T", v)
+	}
+
+	schema := make(map[string]interface{})
+	if err := addStructSchema("", structType, schema, nil); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// NewConfigFromStruct derives the schema from the struct pointed to by target, resolves it like NewConfig
+// and populates the struct with the resolved values.
+func NewConfigFromStruct(target interface{}, opts ...Option) (*Config, error) {
+	// The struct has to be populated, so it must be passed by pointer.
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr || targetValue.IsNil() {
+		return nil, fmt.Errorf("target must be a non-nil pointer to a struct, got %T", target)
+	}
+
+	// Build the schema from the struct tags.
+	schema, err := SchemaFromStruct(target)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the schema like any other configuration.
+	cfg, err := NewConfig(schema, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Populate the struct with the resolved values.
+	if err := cfg.Decode(target); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// addStructSchema adds the schema of every field of a struct type to the schema map.
+// The parents are the struct types on the path to the struct type, which must not contain it again.
+func addStructSchema(path string, structType reflect.Type, schema map[string]interface{}, parents []reflect.Type) error {
+	// Recursive struct types would nest keys forever.
+	if contains(parents, structType) {
+		return fmt.Errorf("field %s: recursive struct type %s", path, structType)
+	}
+	parents = append(parents[:len(parents):len(parents)], structType)
+
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+
+		// Skip unexported fields and fields tagged with "-".
+		name, _, _ := strings.Cut(field.Tag.Get("config"), ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		// Dereference pointer fields.
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		isNamespace := fieldType.Kind() == reflect.Struct && fieldType != timeType
+
+		// Embedded structs without a name share the keys of their parent.
+		if field.Anonymous && name == "" && isNamespace {
+			if err := addStructSchema(path, fieldType, schema, parents); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		keyPath := joinPath(path, name)
+
+		// Nested structs without env or default tags become nested keys.
+		_, hasEnv := field.Tag.Lookup("env")
+		_, hasDefault := field.Tag.Lookup("default")
+		if isNamespace && !hasEnv && !hasDefault {
+			nestedSchema := make(map[string]interface{})
+			if err := addStructSchema(keyPath, fieldType, nestedSchema, parents); err != nil {
+				return err
+			}
+			schema[name] = nestedSchema
+			continue
+		}
+
+		// Every other field becomes a leaf.
+		leaf, err := fieldSchema(keyPath, field, fieldType)
+		if err != nil {
+			return err
+		}
+		schema[name] = leaf
+	}
+	return nil
+}
+
+// fieldSchema builds the schema leaf of a single struct field from its tags.
+func fieldSchema(keyPath string, field reflect.StructField, fieldType reflect.Type) (map[string]interface{}, error) {
+	leaf := make(map[string]interface{})
+
+	// Copy the env and doc tags as they are.
+	if env := field.Tag.Get("env"); env != "" {
+		leaf["env"] = env
+	}
+	if doc := field.Tag.Get("doc"); doc != "" {
+		leaf["doc"] = doc
+	}
+
+	// Use the format tag, or infer the format from the field type.
+	format := field.Tag.Get("format")
+	if format == "" {
+		format = inferFormat(fieldType)
+	}
+	if format != "" {
+		leaf["format"] = format
+	}
+
+	// Mark required fields, which get no zero default so that they have to be set.
+	required := field.Tag.Get("required") == "true"
+	if required {
+		leaf["required"] = true
+	}
+
+	// Parse the default tag into the type declared by the format, or use the zero value of the field type.
+	defaultTag, hasDefault := field.Tag.Lookup("default")
+	switch {
+	case !hasDefault && required:
+	case !hasDefault:
+		leaf["default"] = zeroDefault(fieldType)
+	case fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() != reflect.Uint8:
+		// Slices are written as comma separated lists.
+		items := make([]interface{}, 0)
+		if strings.TrimSpace(defaultTag) != "" {
+			for _, rawItem := range strings.Split(defaultTag, ",") {
+				item, err := parseStringValue(strings.TrimSpace(rawItem), inferFormat(fieldType.Elem()))
+				if err != nil {
+					return nil, fmt.Errorf("field %s: invalid default %q: %w", keyPath, defaultTag, err)
+				}
+				items = append(items, item)
+			}
+		}
+		leaf["default"] = items
+	default:
+		defaultValue, err := parseStringValue(defaultTag, format)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: invalid default %q: %w", keyPath, defaultTag, err)
+		}
+		leaf["default"] = defaultValue
+	}
+	return leaf, nil
+}
+
+// inferFormat returns the format matching the kind of a field type, or an empty string when there is none.
+func inferFormat(fieldType reflect.Type) string {
+	// Durations are stored as strings or numbers, so they have no format of their own.
+	if fieldType == durationType {
+		return ""
+	}
+	switch fieldType.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float64"
+	default:
+		return ""
+	}
+}
+
+// zeroDefault returns the default used for a field without a default tag, matching the format inferred for it.
+func zeroDefault(fieldType reflect.Type) interface{} {
+	if fieldType == durationType {
+		return "0s"
+	}
+	switch inferFormat(fieldType) {
+	case "string":
+		return ""
+	case "bool":
+		return false
+	case "int":
+		return 0
+	case "float64":
+		return float64(0)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,112 @@
+package configmaster
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// parseTOML parses TOML configuration data into the same map shape produced by the JSON decoder.
+func parseTOML(byteValue []byte) (map[string]interface{}, error) {
+	// Unmarshal the byte slice into a map.
+	var config map[string]interface{}
+	if err := toml.Unmarshal(byteValue, &config); err != nil {
+		return nil, err
+	}
+
+	// Normalise arrays of tables and integers so that the rest of the package sees plain slices and numbers.
+	for key, value := range config {
+		config[key] = normalizeValue(value)
+	}
+	return config, nil
+}
+
+// tomlLineIndex returns the line of every key of a TOML document by dotted path.
+// Keys are found by scanning the table headers and the key/value lines, so keys of inline tables are not recorded.
+func tomlLineIndex(byteValue []byte) map[string]int {
+	lines := make(map[string]int)
+	tableCounts := make(map[string]int)
+	table := ""
+	multiline := ""
+
+	for number, line := range strings.Split(string(byteValue), "\n") {
+		line = strings.TrimSpace(line)
+
+		// Skip the lines inside multi-line strings.
+		if multiline != "" {
+			if strings.Count(line, multiline)%2 == 1 {
+				multiline = ""
+			}
+			continue
+		}
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "[["):
+			// Every array of tables header starts the next item of the array.
+			name, _, _ := strings.Cut(line[2:], "]]")
+			path, ok := tomlKeyPath(name)
+			if !ok {
+				continue
+			}
+			if _, exists := lines[path]; !exists {
+				lines[path] = number + 1
+			}
+			table = fmt.Sprintf("%s[%d]", path, tableCounts[path])
+			tableCounts[path]++
+			lines[table] = number + 1
+			continue
+		case strings.HasPrefix(line, "["):
+			// Table headers set the path of the keys that follow them.
+			name, _, _ := strings.Cut(line[1:], "]")
+			path, ok := tomlKeyPath(name)
+			if !ok {
+				continue
+			}
+			table = path
+			lines[table] = number + 1
+			continue
+		}
+
+		// Record the key of a key/value line below the current table.
+		key, value, found := strings.Cut(line, "=")
+		path, ok := tomlKeyPath(key)
+		if !found || !ok {
+			continue
+		}
+		lines[joinPath(table, path)] = number + 1
+
+		// Remember when the value opens a multi-line string.
+		for _, delimiter := range []string{`"""`, `'''`} {
+			if strings.Count(value, delimiter)%2 == 1 {
+				multiline = delimiter
+			}
+		}
+	}
+	return lines
+}
+
+// tomlKeyPath converts a bare, quoted or dotted TOML key into a dotted path.
+func tomlKeyPath(key string) (string, bool) {
+	parts := strings.Split(key, ".")
+	for index, part := range parts {
+		part = strings.TrimSpace(part)
+		if len(part) >= 2 && (part[0] == '"' || part[0] == '\'') && part[len(part)-1] == part[0] {
+			parts[index] = part[1 : len(part)-1]
+			continue
+		}
+		// Bare keys only contain letters, digits, underscores and dashes.
+		if part == "" || strings.TrimFunc(part, isTOMLBareKeyChar) != "" {
+			return "", false
+		}
+		parts[index] = part
+	}
+	return strings.Join(parts, "."), true
+}
+
+// isTOMLBareKeyChar reports whether a character may be used in a bare TOML key.
+func isTOMLBareKeyChar(char rune) bool {
+	return char == '_' || char == '-' || (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9')
+}
@@ -2,9 +2,14 @@ package configmaster
 
 import (
 	"errors"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestProcessRecursively(t *testing.T) {
@@ -95,10 +100,8 @@ func TestProcessRecursively(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			for k, v := range tt.envVars {
-				t.Setenv(k, v)
-			}
-			c := &Config{}
+			t.Parallel()
+			c := &Config{env: MapEnv(tt.envVars)}
 			got, err := c.processRecursively(tt.config)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("processRecursively() error = %v, wantErr %v", err, tt.wantErr)
@@ -207,16 +210,16 @@ func TestNewConfigWithJSONFileContainingEnvironmentVariables(t *testing.T) {
 	}
 	tmpFile.Close()
 
-	// Set the environment variable
-	os.Setenv("FOO", "bar")
-
-	// Test NewConfig with the JSON file
-	cfg, err := NewConfig(tmpFile.Name())
+	// Test NewConfig with the JSON file and the environment variable
+	cfg, err := NewConfig(tmpFile.Name(), WithEnvSource(MapEnv{"FOO": "bar"}))
 	if err != nil {
 		t.Errorf("NewConfig returned error: %v", err)
 	}
 	if cfg == nil {
-		t.Errorf("NewConfig returned nil config")
+		t.Fatalf("NewConfig returned nil config")
+	}
+	if value := cfg.Get("foo"); value != "bar" {
+		t.Errorf("cfg.Get(\"foo\") = %v, want bar", value)
 	}
 }
 
@@ -267,7 +270,7 @@ func TestGet(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cfg := &Config{data: tt.config}
+			cfg := newTestConfig(tt.config)
 			got := cfg.Get(tt.key)
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Get() = %v, want %v", got, tt.want)
@@ -313,11 +316,35 @@ func TestGetNested(t *testing.T) {
 			want: nil,
 			data: map[string]interface{}{"foo": map[string]interface{}{"bar": "baz"}},
 		},
+		{
+			name: "array index in brackets",
+			key:  "xyz[1].bar",
+			want: "baz",
+			data: map[string]interface{}{"xyz": []interface{}{"foo", map[string]interface{}{"bar": "baz"}}},
+		},
+		{
+			name: "array index as segment",
+			key:  "xyz.1.bar",
+			want: "baz",
+			data: map[string]interface{}{"xyz": []interface{}{"foo", map[string]interface{}{"bar": "baz"}}},
+		},
+		{
+			name: "escaped dot",
+			key:  `foo.a\.b`,
+			want: "baz",
+			data: map[string]interface{}{"foo": map[string]interface{}{"a.b": "baz"}},
+		},
+		{
+			name: "quoted key",
+			key:  `foo["a.b"]`,
+			want: "baz",
+			data: map[string]interface{}{"foo": map[string]interface{}{"a.b": "baz"}},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c := &Config{data: tt.data}
+			c := newTestConfig(tt.data)
 			got := c.getNested(tt.key)
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("getNested(%q) = %v, want %v", tt.key, got, tt.want)
@@ -401,22 +428,166 @@ func TestValidateAndSetValue(t *testing.T) {
 				"TEST_ENV": "default value",
 			},
 		},
+		{
+			name: "env var parsed as int",
+			config: map[string]interface{}{
+				"env":     "TEST_ENV",
+				"format":  "int",
+				"default": 8080,
+			},
+			wantValue: 9090,
+			envVars: map[string]string{
+				"TEST_ENV": "9090",
+			},
+		},
+		{
+			name: "env var parsed as float64",
+			config: map[string]interface{}{
+				"env":     "TEST_ENV",
+				"format":  "float64",
+				"default": 0.5,
+			},
+			wantValue: 1.25,
+			envVars: map[string]string{
+				"TEST_ENV": "1.25",
+			},
+		},
+		{
+			name: "env var parsed as bool",
+			config: map[string]interface{}{
+				"env":     "TEST_ENV",
+				"format":  "bool",
+				"default": false,
+			},
+			wantValue: true,
+			envVars: map[string]string{
+				"TEST_ENV": "true",
+			},
+		},
+		{
+			name: "env var not parsable as int",
+			config: map[string]interface{}{
+				"env":     "TEST_ENV",
+				"format":  "int",
+				"default": 8080,
+			},
+			wantErr: true,
+			envVars: map[string]string{
+				"TEST_ENV": "eighty",
+			},
+		},
+		{
+			name: "whole float64 default with int format",
+			config: map[string]interface{}{
+				"format":  "int",
+				"default": float64(8080),
+			},
+			wantValue: 8080,
+		},
+		{
+			name: "fractional float64 default with int format",
+			config: map[string]interface{}{
+				"format":  "int",
+				"default": 80.5,
+			},
+			wantErr: true,
+		},
+		{
+			name: "required key set by env var",
+			config: map[string]interface{}{
+				"env":      "TEST_ENV",
+				"required": true,
+			},
+			wantValue: "secret",
+			envVars: map[string]string{
+				"TEST_ENV": "secret",
+			},
+		},
+		{
+			name: "required key with default",
+			config: map[string]interface{}{
+				"env":      "TEST_ENV",
+				"required": true,
+				"default":  "default value",
+			},
+			wantValue: "default value",
+		},
+		{
+			name: "required key without value",
+			config: map[string]interface{}{
+				"env":      "TEST_ENV",
+				"required": true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "required key without env",
+			config: map[string]interface{}{
+				"format":   "string",
+				"required": true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "required key with null default",
+			config: map[string]interface{}{
+				"required": true,
+				"default":  nil,
+			},
+			wantErr: true,
+		},
+		{
+			name: "nullable key with null default",
+			config: map[string]interface{}{
+				"format":   "int",
+				"nullable": true,
+				"default":  nil,
+			},
+			wantValue: nil,
+		},
+		{
+			name: "nullable key without default",
+			config: map[string]interface{}{
+				"env":      "TEST_ENV",
+				"format":   "int",
+				"nullable": true,
+			},
+			wantValue: nil,
+		},
+		{
+			name: "leaf without env or default",
+			config: map[string]interface{}{
+				"doc":    "some description",
+				"format": "string",
+			},
+			wantValue: nil,
+		},
+		{
+			name: "env var parsed as JSON for object default",
+			config: map[string]interface{}{
+				"env":     "TEST_ENV",
+				"default": map[string]interface{}{"a": float64(1)},
+			},
+			wantValue: map[string]interface{}{"a": float64(2)},
+			envVars: map[string]string{
+				"TEST_ENV": `{"a": 2}`,
+			},
+		},
+		{
+			name: "null default without nullable",
+			config: map[string]interface{}{
+				"format":  "int",
+				"default": nil,
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Set environment variables
-			for key, value := range tt.envVars {
-				os.Setenv(key, value)
-			}
-			defer func() {
-				// Unset environment variables
-				for key := range tt.envVars {
-					os.Unsetenv(key)
-				}
-			}()
-
-			value, err := validateAndSetValue(tt.config)
+			t.Parallel()
+			c := &Config{env: MapEnv(tt.envVars)}
+			value, _, err := c.validateAndSetValue(tt.config, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("validateAndSetValue() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -511,7 +682,7 @@ func TestIsValueInExpectedFormat(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			actual := isValueInExpectedFormat(test.value, test.format)
+			_, actual := isValueInExpectedFormat(test.value, test.format)
 			if actual == nil && test.expected != nil {
 				t.Errorf("expected error, got nil")
 			}
@@ -522,30 +693,36 @@ func TestIsValueInExpectedFormat(t *testing.T) {
 	}
 }
 
-func TestIsNestedMap(t *testing.T) {
+func TestIsSchemaLeaf(t *testing.T) {
 	tests := []struct {
 		name   string
 		config map[string]interface{}
 		want   bool
 	}{
 		{
-			name: "nested key",
+			name: "namespace of nested keys",
 			config: map[string]interface{}{
 				"foo": map[string]interface{}{"bar": "baz"},
 			},
+			want: false,
+		},
+		{
+			name: "leaf with object default",
+			config: map[string]interface{}{
+				"env":     "FOO",
+				"default": map[string]interface{}{"a": 1},
+			},
 			want: true,
 		},
 		{
-			name: "multi nested key",
+			name: "doc-only leaf",
 			config: map[string]interface{}{
-				"foo": map[string]interface{}{
-					"bar": map[string]interface{}{"baz": "qux"},
-				},
+				"doc": "some description",
 			},
 			want: true,
 		},
 		{
-			name: "no nested key",
+			name: "plain keys",
 			config: map[string]interface{}{
 				"foo": "bar",
 			},
@@ -556,6 +733,53 @@ func TestIsNestedMap(t *testing.T) {
 			config: map[string]interface{}{},
 			want:   false,
 		},
+		{
+			name: "namespace with keys named like properties",
+			config: map[string]interface{}{
+				"min": map[string]interface{}{"default": 1, "format": "int"},
+				"max": map[string]interface{}{"default": 10, "format": "int"},
+			},
+			want: false,
+		},
+		{
+			name: "namespace with plain values named like properties",
+			config: map[string]interface{}{
+				"format": "json",
+				"level":  "info",
+			},
+			want: false,
+		},
+		{
+			name: "namespace with a doc",
+			config: map[string]interface{}{
+				"doc":  "Database",
+				"host": map[string]interface{}{"default": "localhost"},
+			},
+			want: false,
+		},
+		{
+			name: "namespace with a key named default",
+			config: map[string]interface{}{
+				"default": map[string]interface{}{"region": "us"},
+				"prod":    map[string]interface{}{"region": "eu"},
+			},
+			want: false,
+		},
+		{
+			name: "leaf with a default and plain values",
+			config: map[string]interface{}{
+				"default": 1,
+				"example": 2,
+			},
+			want: true,
+		},
+		{
+			name: "leaf with items schema",
+			config: map[string]interface{}{
+				"items": map[string]interface{}{"format": "int"},
+			},
+			want: true,
+		},
 		{
 			name:   "nil map",
 			config: nil,
@@ -565,10 +789,1221 @@ func TestIsNestedMap(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := isNestedMap(tt.config)
+			got := isSchemaLeaf(tt.config)
 			if got != tt.want {
-				t.Errorf("isNestedMap(%v) = %v, want %v", tt.config, got, tt.want)
+				t.Errorf("isSchemaLeaf(%v) = %v, want %v", tt.config, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{
+			name:  "int64 becomes int",
+			value: int64(42),
+			want:  42,
+		},
+		{
+			name:  "uint64 becomes int",
+			value: uint64(42),
+			want:  42,
+		},
+		{
+			name:  "map with non-string keys",
+			value: map[interface{}]interface{}{1: "one", "two": int64(2)},
+			want:  map[string]interface{}{"1": "one", "two": 2},
+		},
+		{
+			name:  "nested maps in slices",
+			value: []interface{}{map[interface{}]interface{}{"foo": []interface{}{int64(1)}}},
+			want:  []interface{}{map[string]interface{}{"foo": []interface{}{1}}},
+		},
+		{
+			name:  "array of tables",
+			value: []map[string]interface{}{{"foo": int64(1)}, {"foo": int64(2)}},
+			want:  []interface{}{map[string]interface{}{"foo": 1}, map[string]interface{}{"foo": 2}},
+		},
+		{
+			name:  "other values unchanged",
+			value: 3.14,
+			want:  3.14,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeValue(tt.value)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeValue(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSON5(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:  "plain JSON",
+			input: `{"foo": {"bar": [1, 2.5, true, null, "baz"]}}`,
+			want:  map[string]interface{}{"foo": map[string]interface{}{"bar": []interface{}{1.0, 2.5, true, nil, "baz"}}},
+		},
+		{
+			name: "comments and trailing commas",
+			input: `// leading comment
+			{
+				/* block
+				   comment */
+				"foo": "bar", // trailing comment
+				"baz": [1, 2,],
+			}`,
+			want: map[string]interface{}{"foo": "bar", "baz": []interface{}{1.0, 2.0}},
+		},
+		{
+			name:  "unquoted keys and single-quoted strings",
+			input: `{foo: 'it\'s', $bar_1: "say \"hi\""}`,
+			want:  map[string]interface{}{"foo": "it's", "$bar_1": `say "hi"`},
+		},
+		{
+			name:  "numbers",
+			input: `{hex: 0xFF, negative: -0x10, leading: .5, trailing: 5., plus: +1, exponent: 1e3}`,
+			want:  map[string]interface{}{"hex": 255.0, "negative": -16.0, "leading": 0.5, "trailing": 5.0, "plus": 1.0, "exponent": 1000.0},
+		},
+		{
+			name:  "string escapes and line continuation",
+			input: "{foo: 'a\\nb\\x41\\u00e9\\ud83d\\ude00', bar: 'one \\\ntwo'}",
+			want:  map[string]interface{}{"foo": "a\nbAé😀", "bar": "one two"},
+		},
+		{
+			name:  "empty document",
+			input: `null`,
+			want:  nil,
+		},
+		{
+			name:    "missing comma",
+			input:   "{\n  foo: 1\n  bar: 2\n}",
+			wantErr: `line 3, column 3: expected ',' or '}' in object, got character 'b'`,
+		},
+		{
+			name:    "unterminated string",
+			input:   "{foo: 'bar}",
+			wantErr: "line 1, column 7: unterminated string",
+		},
+		{
+			name:    "unterminated block comment",
+			input:   "{} /* comment",
+			wantErr: "line 1, column 4: unterminated block comment",
+		},
+		{
+			name:    "unknown identifier",
+			input:   "{foo: yes}",
+			wantErr: `line 1, column 7: unexpected identifier "yes"`,
+		},
+		{
+			name:    "top-level array",
+			input:   "[1, 2]",
+			wantErr: "line 1, column 1: top-level value must be an object, got []interface {}",
+		},
+		{
+			name:    "leading zero",
+			input:   "{foo: 01}",
+			wantErr: `line 1, column 7: invalid number "01"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseJSON5([]byte(tt.input))
+			if tt.wantErr != "" {
+				var syntaxError *JSON5SyntaxError
+				if !errors.As(err, &syntaxError) {
+					t.Fatalf("parseJSON5() error = %v, want JSON5SyntaxError", err)
+				}
+				if err.Error() != tt.wantErr {
+					t.Fatalf("parseJSON5() error = %q, want %q", err.Error(), tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseJSON5() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseJSON5() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDotEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:  "plain values",
+			input: "FOO=bar\nBAZ = qux \n",
+			want:  map[string]string{"FOO": "bar", "BAZ": "qux"},
+		},
+		{
+			name:  "comments and blank lines",
+			input: "# comment\n\nFOO=bar # trailing comment\nBAZ=qux#not-a-comment\n",
+			want:  map[string]string{"FOO": "bar", "BAZ": "qux#not-a-comment"},
+		},
+		{
+			name:  "export prefix",
+			input: "export FOO=bar\n",
+			want:  map[string]string{"FOO": "bar"},
+		},
+		{
+			name:  "double-quoted value with escapes",
+			input: `FOO="line one\nline \"two\"" # comment`,
+			want:  map[string]string{"FOO": "line one\nline \"two\""},
+		},
+		{
+			name:  "single-quoted value is literal",
+			input: `FOO='bar\n # baz'`,
+			want:  map[string]string{"FOO": `bar\n # baz`},
+		},
+		{
+			name:  "multi-line value",
+			input: "FOO=\"first\r\nsecond\"\nBAR=baz",
+			want:  map[string]string{"FOO": "first\nsecond", "BAR": "baz"},
+		},
+		{
+			name:  "empty value",
+			input: "FOO=\nBAR=''",
+			want:  map[string]string{"FOO": "", "BAR": ""},
+		},
+		{
+			name:    "missing equals sign",
+			input:   "FOO=bar\nBAZ\n",
+			wantErr: `line 2: expected KEY=VALUE, got "BAZ"`,
+		},
+		{
+			name:    "unterminated quote",
+			input:   "FOO=bar\nBAZ=\"qux\n",
+			wantErr: `line 2: unterminated " quoted value`,
+		},
+		{
+			name:    "content after quoted value",
+			input:   "\nFOO=\"bar\nbaz\" qux\n",
+			wantErr: `line 2: unexpected "qux" after quoted value`,
+		},
+		{
+			name:    "line numbers after multi-line value",
+			input:   "FOO=\"bar\nbaz\"\nBAR\n",
+			wantErr: `line 3: expected KEY=VALUE, got "BAR"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDotEnv([]byte(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("parseDotEnv() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDotEnv() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDotEnv() = %q, want %q", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestEnvSources(t *testing.T) {
+	t.Parallel()
+	chain := ChainEnv{MapEnv{"FOO": "first"}, MapEnv{"FOO": "second", "BAR": "second"}}
+
+	tests := []struct {
+		name      string
+		key       string
+		wantValue string
+		wantFound bool
+	}{
+		{name: "first source wins", key: "FOO", wantValue: "first", wantFound: true},
+		{name: "falls through to later source", key: "BAR", wantValue: "second", wantFound: true},
+		{name: "missing everywhere", key: "BAZ", wantValue: "", wantFound: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			value, found := chain.LookupEnv(tt.key)
+			if value != tt.wantValue || found != tt.wantFound {
+				t.Errorf("LookupEnv(%q) = %q, %v, want %q, %v", tt.key, value, found, tt.wantValue, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestTypedGetters(t *testing.T) {
+	t.Parallel()
+	c := newTestConfig(map[string]interface{}{
+		"name":    "service",
+		"port":    float64(8080),
+		"workers": 4,
+		"ratio":   "0.75",
+		"debug":   "true",
+		"enabled": false,
+		"timeout": "1m30s",
+		"delay":   int64(500),
+		"half":    2.5,
+		"db":      map[string]interface{}{"port": "5432"},
+	})
+
+	tests := []struct {
+		name    string
+		get     func() (interface{}, error)
+		want    interface{}
+		wantErr string
+	}{
+		{
+			name: "string",
+			get:  func() (interface{}, error) { return c.GetString("name") },
+			want: "service",
+		},
+		{
+			name: "int from float64",
+			get:  func() (interface{}, error) { return c.GetInt("port") },
+			want: 8080,
+		},
+		{
+			name: "int from int",
+			get:  func() (interface{}, error) { return c.GetInt("workers") },
+			want: 4,
+		},
+		{
+			name: "int from nested string",
+			get:  func() (interface{}, error) { return c.GetInt("db.port") },
+			want: 5432,
+		},
+		{
+			name: "float64 from string",
+			get:  func() (interface{}, error) { return c.GetFloat64("ratio") },
+			want: 0.75,
+		},
+		{
+			name: "float64 from int",
+			get:  func() (interface{}, error) { return c.GetFloat64("workers") },
+			want: 4.0,
+		},
+		{
+			name: "bool from string",
+			get:  func() (interface{}, error) { return c.GetBool("debug") },
+			want: true,
+		},
+		{
+			name: "bool",
+			get:  func() (interface{}, error) { return c.GetBool("enabled") },
+			want: false,
+		},
+		{
+			name: "duration from string",
+			get:  func() (interface{}, error) { return c.GetDuration("timeout") },
+			want: 90 * time.Second,
+		},
+		{
+			name: "duration from int64",
+			get:  func() (interface{}, error) { return c.GetDuration("delay") },
+			want: 500 * time.Nanosecond,
+		},
+		{
+			name:    "int from fractional float64",
+			get:     func() (interface{}, error) { return c.GetInt("half") },
+			wantErr: `key "half": cannot convert float64 value 2.5 to int`,
+		},
+		{
+			name:    "string from int",
+			get:     func() (interface{}, error) { return c.GetString("workers") },
+			wantErr: `key "workers": cannot convert int value 4 to string`,
+		},
+		{
+			name:    "missing key",
+			get:     func() (interface{}, error) { return c.GetBool("db.debug") },
+			wantErr: `path "db.debug": at "db.debug": key not found`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := tt.get()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v (%T), want %v (%T)", got, got, tt.want, tt.want)
+			}
+		})
+	}
+
+	var typeError *TypeError
+	if _, err := c.GetInt("name"); !errors.As(err, &typeError) || typeError.Key != "name" {
+		t.Errorf("GetInt(\"name\") error = %v, want TypeError for key name", err)
+	}
+	if _, err := c.GetInt("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("GetInt(\"missing\") error = %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestMustGetPanics(t *testing.T) {
+	t.Parallel()
+	c := newTestConfig(map[string]interface{}{"port": 8080})
+
+	if got := c.MustGetInt("port"); got != 8080 {
+		t.Errorf("MustGetInt() = %v, want 8080", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MustGetString() should panic for a value of the wrong type")
+		}
+	}()
+	c.MustGetString("port")
+}
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	type Replica struct {
+		Host string `config:"host"`
+		Port int    `config:"port"`
+	}
+	type Common struct {
+		Name string `config:"name"`
+	}
+	type Database struct {
+		Replicas []Replica         `config:"replicas"`
+		Timeout  time.Duration     `config:"timeout"`
+		Primary  *Replica          `config:"primary"`
+		Labels   map[string]string `config:"labels"`
+	}
+	type Settings struct {
+		Common
+		Debug    bool        `config:"debug"`
+		Ratio    float32     `config:"ratio"`
+		Database Database    `config:"db"`
+		Tags     []string    `config:"tags"`
+		Extra    interface{} `config:"extra"`
+		Ignored  string      `config:"-"`
+		Workers  uint8
+	}
+
+	c := newTestConfig(map[string]interface{}{
+		"name":    "service",
+		"debug":   "true",
+		"ratio":   0.5,
+		"workers": float64(4),
+		"tags":    []interface{}{"a", "b"},
+		"extra":   map[string]interface{}{"foo": "bar"},
+		"Ignored": "ignored",
+		"db": map[string]interface{}{
+			"timeout": "2s",
+			"replicas": []interface{}{
+				map[string]interface{}{"host": "db-1", "port": float64(5432)},
+				map[string]interface{}{"host": "db-2", "port": "5433"},
+			},
+			"primary": map[string]interface{}{"host": "db-0", "port": 5431},
+			"labels":  map[string]interface{}{"zone": "a"},
+		},
+	})
+
+	var settings Settings
+	if err := c.Decode(&settings); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	want := Settings{
+		Common:  Common{Name: "service"},
+		Debug:   true,
+		Ratio:   0.5,
+		Workers: 4,
+		Tags:    []string{"a", "b"},
+		Extra:   map[string]interface{}{"foo": "bar"},
+		Database: Database{
+			Timeout:  2 * time.Second,
+			Replicas: []Replica{{Host: "db-1", Port: 5432}, {Host: "db-2", Port: 5433}},
+			Primary:  &Replica{Host: "db-0", Port: 5431},
+			Labels:   map[string]string{"zone": "a"},
+		},
+	}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("Decode() = %+v, want %+v", settings, want)
+	}
+
+	var replica Replica
+	if err := c.DecodeKey("db.primary", &replica); err != nil {
+		t.Fatalf("DecodeKey() error = %v", err)
+	}
+	if replica != (Replica{Host: "db-0", Port: 5431}) {
+		t.Errorf("DecodeKey() = %+v, want db-0:5431", replica)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	t.Parallel()
+
+	type Replica struct {
+		Port int `config:"port"`
+	}
+	type Database struct {
+		Replicas []Replica `config:"replicas"`
+	}
+
+	c := newTestConfig(map[string]interface{}{
+		"db": map[string]interface{}{
+			"replicas": []interface{}{
+				map[string]interface{}{"port": 5432},
+				map[string]interface{}{"port": "not-a-port"},
+			},
+		},
+	})
+
+	var database Database
+	err := c.DecodeKey("db", &database)
+	var decodeError *DecodeError
+	if !errors.As(err, &decodeError) {
+		t.Fatalf("DecodeKey() error = %v, want DecodeError", err)
+	}
+	if decodeError.Path != "db.replicas[1].port" {
+		t.Errorf("DecodeError.Path = %q, want %q", decodeError.Path, "db.replicas[1].port")
+	}
+
+	if err := c.Decode(database); err == nil {
+		t.Errorf("Decode() should reject a non-pointer target")
+	}
+}
+
+func TestSchemaFromStruct(t *testing.T) {
+	t.Parallel()
+
+	type Common struct {
+		Name string `config:"name" default:"service" doc:"the service name"`
+	}
+	type Database struct {
+		Host     string        `config:"host" env:"DB_HOST" default:"localhost"`
+		Port     int           `config:"port" env:"DB_PORT" default:"5432"`
+		Timeout  time.Duration `config:"timeout" default:"5s"`
+		Password string        `config:"password" env:"DB_PASSWORD" required:"true"`
+	}
+	type Settings struct {
+		Common
+		Debug    bool     `config:"debug" env:"DEBUG"`
+		Ratio    float64  `config:"ratio" default:"0.5"`
+		Mode     string   `config:"mode" format:"string" default:"fast"`
+		Tags     []string `config:"tags" default:"a, b"`
+		Database Database `config:"db"`
+		Ignored  string   `config:"-"`
+		internal string
+	}
+
+	got, err := SchemaFromStruct(&Settings{})
+	if err != nil {
+		t.Fatalf("SchemaFromStruct() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"name":  map[string]interface{}{"format": "string", "default": "service", "doc": "the service name"},
+		"debug": map[string]interface{}{"format": "bool", "default": false, "env": "DEBUG"},
+		"ratio": map[string]interface{}{"format": "float64", "default": 0.5},
+		"mode":  map[string]interface{}{"format": "string", "default": "fast"},
+		"tags":  map[string]interface{}{"default": []interface{}{"a", "b"}},
+		"db": map[string]interface{}{
+			"host":     map[string]interface{}{"format": "string", "default": "localhost", "env": "DB_HOST"},
+			"port":     map[string]interface{}{"format": "int", "default": 5432, "env": "DB_PORT"},
+			"timeout":  map[string]interface{}{"default": "5s"},
+			"password": map[string]interface{}{"format": "string", "env": "DB_PASSWORD", "required": true},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SchemaFromStruct() = %v, want %v", got, want)
+	}
+
+	type Invalid struct {
+		Port int `config:"port" default:"eighty"`
+	}
+	if _, err := SchemaFromStruct(Invalid{}); err == nil {
+		t.Errorf("SchemaFromStruct() should reject a default that does not match the field type")
+	}
+	if _, err := SchemaFromStruct("not a struct"); err == nil {
+		t.Errorf("SchemaFromStruct() should reject values that are not structs")
+	}
+
+	type Node struct {
+		Name string `config:"name"`
+		Next *Node  `config:"next"`
+	}
+	if _, err := SchemaFromStruct(Node{}); err == nil || !strings.Contains(err.Error(), "next") {
+		t.Errorf("SchemaFromStruct() should reject recursive struct types at the recursive field, got %v", err)
+	}
+
+	// The same struct type may still be used by sibling fields
+	type Endpoints struct {
+		Primary   Database `config:"primary"`
+		Secondary Database `config:"secondary"`
+	}
+	if _, err := SchemaFromStruct(Endpoints{}); err != nil {
+		t.Errorf("SchemaFromStruct() error = %v", err)
+	}
+}
+
+func TestMergeValues(t *testing.T) {
+	t.Parallel()
+
+	overlay := map[string]interface{}{
+		"foo": map[string]interface{}{"bar": "overlay"},
+		"baz": []interface{}{"overlay"},
+	}
+	base := map[string]interface{}{
+		"foo": map[string]interface{}{"bar": "base", "qux": "base"},
+		"baz": []interface{}{"base", "base"},
+	}
+	origins := map[string]Source{}
+	overlaySource := Source{Kind: SourceFile, Name: "overlay.json"}
+	c := &Config{}
+	c.mergeValues("", base, overlay, func(string) Source { return overlaySource }, origins)
+
+	want := map[string]interface{}{
+		"foo": map[string]interface{}{"bar": "overlay", "qux": "base"},
+		"baz": []interface{}{"overlay"},
+	}
+	if !reflect.DeepEqual(base, want) {
+		t.Errorf("mergeValues() = %v, want %v", base, want)
+	}
+
+	wantOrigins := map[string]Source{"foo": overlaySource, "foo.bar": overlaySource, "baz": overlaySource}
+	if !reflect.DeepEqual(origins, wantOrigins) {
+		t.Errorf("mergeValues() origins = %v, want %v", origins, wantOrigins)
+	}
+}
+
+func TestMergeSlices(t *testing.T) {
+	t.Parallel()
+
+	base := map[string]interface{}{
+		"hosts": []interface{}{"a", "b"},
+		"replicas": []interface{}{
+			map[string]interface{}{"host": "db-1", "port": 5432},
+			map[string]interface{}{"host": "db-2", "port": 5432},
+		},
+		"name": "base",
+	}
+	overlay := map[string]interface{}{
+		"hosts": []interface{}{"c"},
+		"replicas": []interface{}{
+			nil,
+			map[string]interface{}{"port": 6543},
+			map[string]interface{}{"host": "db-3"},
+		},
+		"name": nil,
+	}
+
+	tests := []struct {
+		name       string
+		strategies map[string]SliceStrategy
+		want       map[string]interface{}
+	}{
+		{
+			name: "replace by default",
+			want: map[string]interface{}{
+				"hosts":    []interface{}{"c"},
+				"replicas": overlay["replicas"],
+				"name":     "base",
+			},
+		},
+		{
+			name:       "append",
+			strategies: map[string]SliceStrategy{"hosts": SliceAppend},
+			want: map[string]interface{}{
+				"hosts":    []interface{}{"a", "b", "c"},
+				"replicas": overlay["replicas"],
+				"name":     "base",
+			},
+		},
+		{
+			name:       "merge by index",
+			strategies: map[string]SliceStrategy{"replicas": SliceMergeByIndex},
+			want: map[string]interface{}{
+				"hosts": []interface{}{"c"},
+				"replicas": []interface{}{
+					map[string]interface{}{"host": "db-1", "port": 5432},
+					map[string]interface{}{"host": "db-2", "port": 6543},
+					map[string]interface{}{"host": "db-3"},
+				},
+				"name": "base",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := &Config{sliceStrategies: tt.strategies}
+			merged := copyMap(base)
+			c.mergeValues("", merged, overlay, func(string) Source { return Source{Kind: SourceMerge} }, map[string]Source{})
+			if !reflect.DeepEqual(merged, tt.want) {
+				t.Errorf("mergeValues() = %v, want %v", merged, tt.want)
+			}
+		})
+	}
+
+	// The inputs are never modified
+	if !reflect.DeepEqual(base["hosts"], []interface{}{"a", "b"}) || overlay["name"] != nil {
+		t.Errorf("mergeValues() should not modify its inputs")
+	}
+}
+
+func TestLineIndex(t *testing.T) {
+	tests := []struct {
+		name   string
+		format FileFormat
+		input  string
+		want   map[string]int
+	}{
+		{
+			name:   "json",
+			format: FormatJSON,
+			input:  "{\n  \"db\": {\n    \"port\": 5432\n  },\n  \"hosts\": [\n    \"a\",\n    {\"name\": \"b\"}\n  ]\n}\n",
+			want:   map[string]int{"db": 2, "db.port": 3, "hosts": 5, "hosts[0]": 6, "hosts[1]": 7, "hosts[1].name": 7},
+		},
+		{
+			name:   "json5",
+			format: FormatJSON5,
+			input:  "// comment\n{\n  db: {\n    /* port */ port: 5432,\n  },\n}\n",
+			want:   map[string]int{"db": 3, "db.port": 4},
+		},
+		{
+			name:   "yaml",
+			format: FormatYAML,
+			input:  "# comment\ndb:\n  port: 5432\nhosts:\n  - a\n  - name: b\n",
+			want:   map[string]int{"db": 2, "db.port": 3, "hosts": 4, "hosts[0]": 5, "hosts[1]": 6, "hosts[1].name": 6},
+		},
+		{
+			name:   "toml",
+			format: FormatTOML,
+			input:  "title = \"x\"\n\n[db]\nport = 5432\n\"max conns\" = 10\nnote = \"\"\"\nkey = value\n\"\"\"\n\n[[hosts]]\nname = \"a\"\n\n[[hosts]]\nname = \"b\"\n",
+			want: map[string]int{
+				"title": 1, "db": 3, "db.port": 4, "db.max conns": 5, "db.note": 6,
+				"hosts": 10, "hosts[0]": 10, "hosts[0].name": 11, "hosts[1]": 13, "hosts[1].name": 14,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			c.recordLines("config", tt.format, []byte(tt.input))
+			if got := c.fileLines["config"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("recordLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourceString(t *testing.T) {
+	tests := []struct {
+		name   string
+		source Source
+		want   string
+	}{
+		{name: "default", source: Source{Kind: SourceDefault}, want: "default"},
+		{name: "default from schema file", source: Source{Kind: SourceDefault, Name: "schema.json", Line: 4}, want: "default:schema.json:4"},
+		{name: "env", source: Source{Kind: SourceEnv, Name: "PORT"}, want: "env:PORT"},
+		{name: "file", source: Source{Kind: SourceFile, Name: "config.yaml", Line: 12}, want: "file:config.yaml:12"},
+		{name: "file without line", source: Source{Kind: SourceFile, Name: "config.yaml"}, want: "file:config.yaml"},
+		{name: "values", source: Source{Kind: SourceValues, Name: "values[1]"}, want: "values[1]"},
+		{name: "merge", source: Source{Kind: SourceMerge}, want: "merge"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.source.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckConstraints(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		config   map[string]interface{}
+		wantRule string
+		wantErr  string
+	}{
+		{name: "within range", value: 8080, config: map[string]interface{}{"min": 1, "max": float64(65535)}},
+		{name: "below min", value: 0, config: map[string]interface{}{"min": 1}, wantRule: "min", wantErr: "value 0 is less than the minimum 1"},
+		{name: "above max", value: 70000.5, config: map[string]interface{}{"max": 65535}, wantRule: "max", wantErr: "value 70000.5 is greater than the maximum 65535"},
+		{name: "multiple of int", value: 30, config: map[string]interface{}{"multipleOf": 5}},
+		{name: "multiple of float", value: 0.3, config: map[string]interface{}{"multipleOf": 0.1}},
+		{name: "not a multiple", value: 7, config: map[string]interface{}{"multipleOf": 5}, wantRule: "multipleOf", wantErr: "value 7 is not a multiple of 5"},
+		{name: "non-positive multipleOf", value: 7, config: map[string]interface{}{"multipleOf": 0}, wantRule: "multipleOf", wantErr: "invalid multipleOf constraint 0, expected a positive number"},
+		{name: "non-numeric min", value: 7, config: map[string]interface{}{"min": "1"}, wantRule: "min", wantErr: `invalid min constraint "1", expected a number`},
+		{name: "numeric constraints skip strings", value: "7", config: map[string]interface{}{"min": 10}},
+		{name: "within length", value: "héllo", config: map[string]interface{}{"minLength": 5, "maxLength": float64(5)}},
+		{name: "too short", value: "ab", config: map[string]interface{}{"minLength": 3}, wantRule: "minLength", wantErr: `length 2 of value "ab" is less than the minimum length 3`},
+		{name: "too long", value: "abcd", config: map[string]interface{}{"maxLength": 3}, wantRule: "maxLength", wantErr: `length 4 of value "abcd" is greater than the maximum length 3`},
+		{name: "matches pattern", value: "v1.2.3", config: map[string]interface{}{"pattern": `^v\d+\.\d+\.\d+$`}},
+		{name: "does not match pattern", value: "latest", config: map[string]interface{}{"pattern": `^v\d+$`}, wantRule: "pattern", wantErr: `value "latest" does not match the pattern "^v\\d+$"`},
+		{name: "invalid pattern", value: "x", config: map[string]interface{}{"pattern": "("}, wantRule: "pattern", wantErr: "invalid pattern constraint"},
+		{name: "string constraints skip numbers", value: 12345, config: map[string]interface{}{"maxLength": 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := checkConstraints(tt.value, tt.config)
+			if rule != tt.wantRule {
+				t.Errorf("checkConstraints() rule = %v, want %v", rule, tt.wantRule)
+			}
+			if (err != nil) != (tt.wantErr != "") || (err != nil && !strings.HasPrefix(err.Error(), tt.wantErr)) {
+				t.Errorf("checkConstraints() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSemanticFormats(t *testing.T) {
+	timestamp := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		format  string
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "port", format: "port", value: 8080, want: 8080},
+		{name: "port out of range", format: "port", value: 70000, wantErr: true},
+		{name: "port not a number", format: "port", value: "8080", wantErr: true},
+		{name: "nat", format: "nat", value: 0, want: 0},
+		{name: "negative nat", format: "nat", value: -1, wantErr: true},
+		{name: "url", format: "url", value: "https://example.com/path?q=1", want: &url.URL{Scheme: "https", Host: "example.com", Path: "/path", RawQuery: "q=1"}},
+		{name: "relative url", format: "url", value: "/path", wantErr: true},
+		{name: "email", format: "email", value: "ops@example.com", want: "ops@example.com"},
+		{name: "email with name", format: "email", value: "Ops <ops@example.com>", wantErr: true},
+		{name: "invalid email", format: "email", value: "ops.example.com", wantErr: true},
+		{name: "ipaddress v4", format: "ipaddress", value: "10.0.0.1", want: net.ParseIP("10.0.0.1")},
+		{name: "ipaddress v6", format: "ipaddress", value: "::1", want: net.ParseIP("::1")},
+		{name: "invalid ipaddress", format: "ipaddress", value: "10.0.0.256", wantErr: true},
+		{name: "ipv4", format: "ipv4", value: "192.168.1.1", want: net.ParseIP("192.168.1.1")},
+		{name: "ipv4 given v6", format: "ipv4", value: "fe80::1", wantErr: true},
+		{name: "ipv6", format: "ipv6", value: "fe80::1", want: net.ParseIP("fe80::1")},
+		{name: "ipv6 given v4", format: "ipv6", value: "192.168.1.1", wantErr: true},
+		{name: "hostname", format: "hostname", value: "db-1.internal.example.com.", want: "db-1.internal.example.com."},
+		{name: "hostname with underscore", format: "hostname", value: "db_1.example.com", wantErr: true},
+		{name: "hostname with leading hyphen", format: "hostname", value: "-db.example.com", wantErr: true},
+		{name: "duration", format: "duration", value: "1m30s", want: 90 * time.Second},
+		{name: "duration in nanoseconds", format: "duration", value: 1000, want: time.Microsecond},
+		{name: "invalid duration", format: "duration", value: "soon", wantErr: true},
+		{name: "timestamp", format: "timestamp", value: "2024-05-01T12:30:00Z", want: timestamp},
+		{name: "decoded timestamp", format: "timestamp", value: timestamp, want: timestamp},
+		{name: "invalid timestamp", format: "timestamp", value: "2024-05-01", wantErr: true},
+		{name: "cron", format: "cron", value: "*/15 0-6,22 1 JAN-MAR mon-fri", want: "*/15 0-6,22 1 JAN-MAR mon-fri"},
+		{name: "cron macro", format: "cron", value: "@daily", want: "@daily"},
+		{name: "cron every", format: "cron", value: "@every 5m", want: "@every 5m"},
+		{name: "cron out of range", format: "cron", value: "60 * * * *", wantErr: true},
+		{name: "cron with missing field", format: "cron", value: "* * * *", wantErr: true},
+		{name: "cron with unknown macro", format: "cron", value: "@sometimes", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := isValueInExpectedFormat(tt.value, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("isValueInExpectedFormat() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("isValueInExpectedFormat() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatRegistry(t *testing.T) {
+	t.Parallel()
+
+	// Formats registered for the package are used by every configuration
+	RegisterFormat("Registry-Test-Upper", func(value interface{}) (interface{}, error) {
+		text, ok := value.(string)
+		if !ok {
+			return nil, errors.New("value is not a string")
+		}
+		return strings.ToUpper(text), nil
+	})
+	c := &Config{}
+	if got, err := c.formatValue("abc", "registry-test-upper"); err != nil || got != "ABC" {
+		t.Errorf("formatValue() = %v, %v, want ABC", got, err)
+	}
+	if _, err := c.formatValue(1, "registry-test-upper"); err == nil {
+		t.Errorf("formatValue() should return the error of the format")
+	}
+
+	// Formats registered for a configuration take precedence
+	c.RegisterFormat("registry-test-upper", func(value interface{}) (interface{}, error) {
+		return "local", nil
+	})
+	if got, err := c.formatValue("abc", "registry-test-upper"); err != nil || got != "local" {
+		t.Errorf("formatValue() = %v, %v, want local", got, err)
+	}
+	if got, err := (&Config{}).formatValue("abc", "registry-test-upper"); err != nil || got != "ABC" {
+		t.Errorf("formatValue() = %v, %v, want ABC", got, err)
+	}
+
+	// Unknown formats are rejected instead of accepted silently
+	if _, err := c.formatValue("abc", "registry-test-unknown"); err == nil || err.Error() != `unknown format "registry-test-unknown"` {
+		t.Errorf("formatValue() error = %v, want unknown format", err)
+	}
+
+	invalid := []struct {
+		name    string
+		fmtName string
+		fn      FormatFunc
+	}{
+		{name: "empty name", fmtName: "", fn: func(value interface{}) (interface{}, error) { return value, nil }},
+		{name: "built-in name", fmtName: "Port", fn: func(value interface{}) (interface{}, error) { return value, nil }},
+		{name: "nil function", fmtName: "registry-test-nil"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterFormat() should panic")
+				}
+			}()
+			RegisterFormat(tt.fmtName, tt.fn)
+		})
+	}
+}
+
+func TestLintSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   map[string]interface{}
+		wantKeys []string
+	}{
+		{
+			name: "valid schema",
+			schema: map[string]interface{}{
+				"port": map[string]interface{}{"env": "PORT", "format": "port", "default": float64(8080), "min": 1, "doc": "the port"},
+				"mode": map[string]interface{}{"format": []interface{}{"fast", "slow"}, "default": "fast"},
+				"db":   map[string]interface{}{"password": map[string]interface{}{"env": "DB_PASSWORD", "required": true}},
+				"tags": map[string]interface{}{"xyz": "plain value"},
+			},
+		},
+		{
+			name:     "unknown format name",
+			schema:   map[string]interface{}{"port": map[string]interface{}{"format": "integer", "default": 1}},
+			wantKeys: []string{"port"},
+		},
+		{
+			name:     "non-string env",
+			schema:   map[string]interface{}{"port": map[string]interface{}{"env": 8080}},
+			wantKeys: []string{"port"},
+		},
+		{
+			name:     "default not matching the format",
+			schema:   map[string]interface{}{"db": map[string]interface{}{"port": map[string]interface{}{"format": "int", "default": "x"}}},
+			wantKeys: []string{"db.port"},
+		},
+		{
+			name:   "null default of nullable key",
+			schema: map[string]interface{}{"port": map[string]interface{}{"format": "int", "default": nil, "nullable": true}},
+		},
+		{
+			name:     "unsupported property",
+			schema:   map[string]interface{}{"port": map[string]interface{}{"env": "PORT", "defualt": 1}},
+			wantKeys: []string{"port"},
+		},
+		{
+			name:     "non-bool required",
+			schema:   map[string]interface{}{"port": map[string]interface{}{"env": "PORT", "required": "yes"}},
+			wantKeys: []string{"port"},
+		},
+		{
+			name: "items schemas",
+			schema: map[string]interface{}{
+				"ports": map[string]interface{}{"format": "array", "default": []interface{}{}, "items": map[string]interface{}{"format": "integer"}},
+				"hosts": map[string]interface{}{"format": "string", "items": map[string]interface{}{"name": map[string]interface{}{"env": 1}}},
+				"tags":  map[string]interface{}{"default": []interface{}{}, "items": "string"},
+			},
+			wantKeys: []string{"hosts", "hosts.items.name", "ports.items", "tags"},
+		},
+		{
+			name: "namespace with keys named like properties",
+			schema: map[string]interface{}{
+				"pool": map[string]interface{}{
+					"min": map[string]interface{}{"default": 1, "format": "int"},
+					"max": map[string]interface{}{"default": 10, "format": "int"},
+				},
+			},
+		},
+		{
+			name: "ambiguous nodes",
+			schema: map[string]interface{}{
+				"pool":  map[string]interface{}{"min": map[string]interface{}{"dfault": 1}},
+				"port":  map[string]interface{}{"default": 1, "max": map[string]interface{}{"default": 10}},
+				"limit": map[string]interface{}{"format": "int", "default": 1},
+			},
+			wantKeys: []string{"pool", "port"},
+		},
+		{
+			name: "leaves inside slices",
+			schema: map[string]interface{}{
+				"xyz": []interface{}{"a", map[string]interface{}{"bar": map[string]interface{}{"format": "integer", "env": 1}}},
+			},
+			wantKeys: []string{"xyz[1].bar", "xyz[1].bar"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{schema: tt.schema}
+			err := c.lintSchema()
+
+			var gotKeys []string
+			var schemaErrors ValidationErrors
+			if errors.As(err, &schemaErrors) {
+				for _, schemaError := range schemaErrors {
+					if schemaError.Rule != "schema" {
+						t.Errorf("lintSchema() rule = %v, want schema", schemaError.Rule)
+					}
+					gotKeys = append(gotKeys, schemaError.Key)
+				}
+			} else if err != nil {
+				t.Fatalf("lintSchema() error = %v, want ValidationErrors", err)
+			}
+			if !reflect.DeepEqual(gotKeys, tt.wantKeys) {
+				t.Errorf("lintSchema() keys = %v, want %v (%v)", gotKeys, tt.wantKeys, err)
+			}
+		})
+	}
+}
+
+func TestSuggestKey(t *testing.T) {
+	declared := map[string]interface{}{"database": nil, "port": nil, "hosts": nil}
+	tests := []struct {
+		key       string
+		want      string
+		wantFound bool
+	}{
+		{key: "dtabase", want: "database", wantFound: true},
+		{key: "Database", want: "database", wantFound: true},
+		{key: "prot", want: "port", wantFound: true},
+		{key: "host", want: "hosts", wantFound: true},
+		{key: "timeout", wantFound: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, found := suggestKey(tt.key, declared)
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("suggestKey() = %v, %v, want %v, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "", b: "abc", want: 3},
+		{a: "database", b: "database", want: 0},
+		{a: "dtabase", b: "database", want: 1},
+		{a: "kitten", b: "sitting", want: 3},
+		{a: "héllo", b: "hello", want: 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []pathSegment
+		wantErr bool
+	}{
+		{path: "foo", want: []pathSegment{{key: "foo"}}},
+		{path: "foo.bar", want: []pathSegment{{key: "foo"}, {key: "bar"}}},
+		{path: "xyz[1].bar", want: []pathSegment{{key: "xyz"}, {index: 1, isIndex: true}, {key: "bar"}}},
+		{path: "xyz[0][2]", want: []pathSegment{{key: "xyz"}, {index: 0, isIndex: true}, {index: 2, isIndex: true}}},
+		{path: "xyz.1.bar", want: []pathSegment{{key: "xyz"}, {key: "1"}, {key: "bar"}}},
+		{path: `a\.b.c`, want: []pathSegment{{key: "a.b"}, {key: "c"}}},
+		{path: `a["b.c"]`, want: []pathSegment{{key: "a"}, {key: "b.c"}}},
+		{path: `a['b"c'].d`, want: []pathSegment{{key: "a"}, {key: `b"c`}, {key: "d"}}},
+		{path: `a["b\"c"]`, want: []pathSegment{{key: "a"}, {key: `b"c`}}},
+		{path: `["a.b"]`, want: []pathSegment{{key: "a.b"}}},
+		{path: "", wantErr: true},
+		{path: "foo.", wantErr: true},
+		{path: ".foo", wantErr: true},
+		{path: "foo..bar", wantErr: true},
+		{path: "foo.[0]", wantErr: true},
+		{path: "foo[", wantErr: true},
+		{path: "foo[x]", wantErr: true},
+		{path: "foo[-1]", wantErr: true},
+		{path: "foo[0]bar", wantErr: true},
+		{path: `foo["bar]`, wantErr: true},
+		{path: `foo\`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parsePath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidPath) {
+				t.Errorf("parsePath() error = %v, want ErrInvalidPath", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	c := newTestConfig(map[string]interface{}{
+		"xyz":  []interface{}{"foo", map[string]interface{}{"bar": "baz"}},
+		"port": 8080,
+		"none": nil,
+	})
+	tests := []struct {
+		key         string
+		want        interface{}
+		wantErr     string
+		wantMissing bool
+	}{
+		{key: "xyz[1].bar", want: "baz"},
+		{key: "xyz[0]", want: "foo"},
+		{key: "xyz[2].bar", wantErr: `path "xyz[2].bar": at "xyz[2]": key not found: index 2 out of range for array of length 2`, wantMissing: true},
+		{key: "xyz.one", wantErr: `path "xyz.one": at "xyz.one": cannot look up key "one" in an array`},
+		{key: "xyz[1].qux", wantErr: `path "xyz[1].qux": at "xyz[1].qux": key not found`, wantMissing: true},
+		{key: "port.number", wantErr: `path "port.number": at "port.number": cannot traverse int value at port`},
+		{key: "none.key", wantErr: `path "none.key": at "none.key": key not found: none is null`, wantMissing: true},
+		{key: "xyz[", wantErr: `path "xyz[": invalid path: unterminated '[' at offset 3`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := c.Lookup(tt.key)
+			if tt.wantErr == "" {
+				if err != nil || !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Lookup() = %v, %v, want %v", got, err, tt.want)
+				}
+				return
+			}
+			var pathError *PathError
+			if !errors.As(err, &pathError) || err.Error() != tt.wantErr {
+				t.Fatalf("Lookup() error = %v, want %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrKeyNotFound) != tt.wantMissing {
+				t.Errorf("Lookup() error = %v, ErrKeyNotFound %v", err, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestEditPath(t *testing.T) {
+	set := func(interface{}, bool) (interface{}, bool, error) { return "new", true, nil }
+	remove := func(_ interface{}, exists bool) (interface{}, bool, error) {
+		if !exists {
+			return nil, false, ErrKeyNotFound
+		}
+		return nil, false, nil
+	}
+	tests := []struct {
+		name     string
+		key      string
+		create   bool
+		edit     editFunc
+		want     map[string]interface{}
+		wantPath string
+		wantErr  string
+	}{
+		{name: "set nested key", key: "db.host", create: true, edit: set, wantPath: "db.host",
+			want: map[string]interface{}{"db": map[string]interface{}{"host": "new", "port": 5432}, "xyz": []interface{}{"foo", map[string]interface{}{"bar": "baz"}}}},
+		{name: "create intermediate maps", key: "cache.redis.url", create: true, edit: set, wantPath: "cache.redis.url",
+			want: map[string]interface{}{"db": map[string]interface{}{"host": "localhost", "port": 5432}, "xyz": []interface{}{"foo", map[string]interface{}{"bar": "baz"}}, "cache": map[string]interface{}{"redis": map[string]interface{}{"url": "new"}}}},
+		{name: "set array item", key: "xyz.1.bar", create: true, edit: set, wantPath: "xyz[1].bar",
+			want: map[string]interface{}{"db": map[string]interface{}{"host": "localhost", "port": 5432}, "xyz": []interface{}{"foo", map[string]interface{}{"bar": "new"}}}},
+		{name: "delete key", key: "db.port", edit: remove, wantPath: "db.port",
+			want: map[string]interface{}{"db": map[string]interface{}{"host": "localhost"}, "xyz": []interface{}{"foo", map[string]interface{}{"bar": "baz"}}}},
+		{name: "delete array item", key: "xyz[0]", edit: remove, wantPath: "xyz[0]",
+			want: map[string]interface{}{"db": map[string]interface{}{"host": "localhost", "port": 5432}, "xyz": []interface{}{map[string]interface{}{"bar": "baz"}}}},
+		{name: "delete missing key", key: "db.user", edit: remove, wantErr: `path "db.user": at "db.user": key not found`},
+		{name: "missing intermediate map", key: "cache.url", edit: remove, wantErr: `path "cache.url": at "cache": key not found`},
+		{name: "index out of range", key: "xyz[2]", create: true, edit: set, wantErr: `path "xyz[2]": at "xyz[2]": key not found: index 2 out of range for array of length 2`},
+		{name: "traverse scalar", key: "db.port.number", create: true, edit: set, wantErr: `path "db.port.number": at "db.port.number": cannot traverse int value at db.port`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]interface{}{
+				"db":  map[string]interface{}{"host": "localhost", "port": 5432},
+				"xyz": []interface{}{"foo", map[string]interface{}{"bar": "baz"}},
+			}
+			segments, err := parsePath(tt.key)
+			if err != nil {
+				t.Fatalf("parsePath() error = %v", err)
+			}
+			got, path, err := editPath(tt.key, data, segments, "", tt.create, tt.edit)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("editPath() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || path != tt.wantPath || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("editPath() = %v, %q, %v, want %v, %q", got, path, err, tt.want, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestWatchedFiles(t *testing.T) {
+	absolute := func(filename string) string {
+		path, err := filepath.Abs(filename)
+		if err != nil {
+			t.Fatalf("filepath.Abs() error = %v", err)
+		}
+		return path
+	}
+	c := &Config{
+		input:        "./sample-config.json",
+		valueSources: []interface{}{"base.yaml", map[string]interface{}{"port": 8080}, "./sample-config.json"},
+		dotEnvFiles:  []string{".env"},
+	}
+	want := []string{absolute("sample-config.json"), absolute("base.yaml"), absolute(".env")}
+	if got := c.watchedFiles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("watchedFiles() = %v, want %v", got, want)
+	}
+	if got := (&Config{input: map[string]interface{}{}}).watchedFiles(); len(got) != 0 {
+		t.Errorf("watchedFiles() = %v, want none", got)
+	}
+}
+
+// newTestConfig returns a configuration that resolved to the given data.
+func newTestConfig(data map[string]interface{}) *Config {
+	c := &Config{}
+	c.state.Store(&snapshot{data: data})
+	return c
+}
@@ -0,0 +1,106 @@
+package configmaster
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// UnknownKeyMode decides what happens to keys of value files and maps that the schema does not declare.
+type UnknownKeyMode int
+
+const (
+	// UnknownKeysAllow keeps undeclared keys in the resolved data.
+	UnknownKeysAllow UnknownKeyMode = iota
+	// UnknownKeysWarn keeps undeclared keys in the resolved data and logs a warning for each of them the first time it is seen.
+	UnknownKeysWarn
+	// UnknownKeysReject reports every undeclared key as a validation error.
+	UnknownKeysReject
+)
+
+// checkUnknownKey handles a key of the value sources that the schema does not declare according to the unknown key mode.
+// It reports whether the key is kept in the resolved data.
+func (c *Config) checkUnknownKey(path string, key string, value interface{}, declared map[string]interface{}, state *processState) bool {
+	if c.unknownKeys == UnknownKeysAllow {
+		return true
+	}
+
+	// Describe the key, suggesting the closest declared key.
+	keyPath := joinPath(path, key)
+	err := fmt.Errorf("key is not declared in the schema")
+	if suggestion, found := suggestKey(key, declared); found {
+		err = fmt.Errorf("key is not declared in the schema, did you mean %q?", joinPath(path, suggestion))
+	}
+
+	if c.unknownKeys == UnknownKeysWarn {
+		// Warn once per key, as Set, Delete, Merge and Reload resolve every key again.
+		if c.warnedKeys[keyPath] {
+			return true
+		}
+		if c.warnedKeys == nil {
+			c.warnedKeys = make(map[string]bool)
+		}
+		c.warnedKeys[keyPath] = true
+
+		c.warnf("%s: %v (from %s)", keyPath, err, state.origin(keyPath))
+		return true
+	}
+	state.errors = append(state.errors, &ValidationError{
+		Key: keyPath, Value: value, Source: state.origin(keyPath), Rule: "unknown", Err: err,
+	})
+	return false
+}
+
+// warnf logs a warning with the logger set by WithLogger, or with the standard logger.
+func (c *Config) warnf(format string, args ...interface{}) {
+	logger := c.logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Printf("[Config-Master]: "+format, args...)
+}
+
+// suggestKey returns the declared key closest to an undeclared one by edit distance, if any is close enough to be a typo.
+func suggestKey(key string, declared map[string]interface{}) (string, bool) {
+	// Sort the declared keys so that ties are broken the same way every time.
+	candidates := make([]string, 0, len(declared))
+	for candidate := range declared {
+		candidates = append(candidates, candidate)
+	}
+	sort.Strings(candidates)
+
+	// Allow about one edit for every three characters, and at least two.
+	best, bestDistance := "", max(2, len(key)/3)+1
+	for _, candidate := range candidates {
+		distance := editDistance(strings.ToLower(key), strings.ToLower(candidate))
+		if distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best, best != ""
+}
+
+// editDistance returns the Levenshtein distance between two strings, counted in characters.
+func editDistance(a string, b string) int {
+	source, target := []rune(a), []rune(b)
+
+	// Keep only the previous row of the distance matrix.
+	previous := make([]int, len(target)+1)
+	for index := range previous {
+		previous[index] = index
+	}
+	for i := 1; i <= len(source); i++ {
+		current := make([]int, len(target)+1)
+		current[0] = i
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(target)]
+}
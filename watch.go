@@ -0,0 +1,195 @@
+package configmaster
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// defaultPollInterval is the interval at which files are polled when file system notifications are not available.
+const defaultPollInterval = time.Second
+
+// watchDebounce is how long Watch waits after the last change before reloading, so that a burst of writes reloads once.
+const watchDebounce = 100 * time.Millisecond
+
+// watcher reports changes of the watched files until it is closed.
+type watcher struct {
+	changes chan struct{}
+	errors  chan error
+	close   func()
+}
+
+// newWatcher creates a watcher that can report a change and an error without blocking.
+func newWatcher() *watcher {
+	return &watcher{changes: make(chan struct{}, 1), errors: make(chan error, 1)}
+}
+
+// notify reports a change, merging it with a change that was not received yet.
+func (w *watcher) notify() {
+	select {
+	case w.changes <- struct{}{}:
+	default:
+	}
+}
+
+// Watch watches the schema file, the value files and the .env files passed to NewConfig and reloads the configuration when they change.
+//
+// Changes are debounced so that a burst of writes reloads once. Every reload is validated like Reload and only replaces the values
+// if they validate, otherwise the last good values are kept. The result of the latest reload, nil on success, is sent on the returned
+// channel, replacing a result that was not received yet, and the channel is closed when the context is done. Files are watched with
+// inotify on Linux and polled elsewhere, or when WithWatchPolling is used. Watching falls back to polling if inotify cannot be set up or fails.
+func (c *Config) Watch(ctx context.Context) (<-chan error, error) {
+	if c.frozen {
+		return nil, ErrFrozen
+	}
+
+	// Collect the files to watch.
+	files := c.watchedFiles()
+	if len(files) == 0 {
+		return nil, errors.New("[Config-Master]: no files to watch")
+	}
+
+	// Prefer file system notifications and fall back to polling when they are not available.
+	w, interval := c.openWatcher(files, newNativeWatcher)
+
+	results := make(chan error, 1)
+	go c.watch(ctx, w, files, interval, results)
+	return results, nil
+}
+
+// openWatcher creates a watcher with the native constructor unless WithWatchPolling was used and returns it with the poll interval.
+// Files are polled instead when the native watcher cannot be set up, e.g. when the inotify limits are reached.
+func (c *Config) openWatcher(files []string, native func([]string) (*watcher, error)) (*watcher, time.Duration) {
+	if c.pollInterval > 0 {
+		return newPollWatcher(files, c.pollInterval), c.pollInterval
+	}
+	w, err := native(files)
+	if err != nil {
+		if !errors.Is(err, errors.ErrUnsupported) {
+			c.warnf("polling files: %v", err)
+		}
+		return newPollWatcher(files, defaultPollInterval), defaultPollInterval
+	}
+	return w, defaultPollInterval
+}
+
+// watch reloads the configuration after the changes reported by the watcher until the context is done.
+// If the watcher fails, the files are polled at the interval instead.
+func (c *Config) watch(ctx context.Context, w *watcher, files []string, interval time.Duration, results chan error) {
+	defer close(results)
+	defer func() { w.close() }()
+
+	// report sends the result of a reload without blocking, dropping a result that was not received yet,
+	// so that a caller that stops receiving does not stop the reloads.
+	report := func(err error) {
+		for {
+			select {
+			case results <- err:
+				return
+			default:
+			}
+			select {
+			case <-results:
+			default:
+			}
+		}
+	}
+
+	// Wait for the changes to settle before reloading.
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.changes:
+			debounce.Reset(watchDebounce)
+		case err := <-w.errors:
+			// Poll the files instead and reload, as changes may have been missed.
+			report(err)
+			w.close()
+			w = newPollWatcher(files, interval)
+			debounce.Reset(watchDebounce)
+		case <-debounce.C:
+			report(c.Reload())
+		}
+	}
+}
+
+// watchedFiles returns the absolute paths of the files the configuration was loaded from.
+func (c *Config) watchedFiles() []string {
+	var files []string
+	add := func(source interface{}) {
+		filename, ok := source.(string)
+		if !ok {
+			return
+		}
+		if absolute, err := filepath.Abs(filename); err == nil {
+			filename = absolute
+		}
+		if !contains(files, filename) {
+			files = append(files, filename)
+		}
+	}
+
+	add(c.input)
+	for _, source := range c.valueSources {
+		add(source)
+	}
+	for _, filename := range c.dotEnvFiles {
+		add(filename)
+	}
+	return files
+}
+
+// fileState is the part of the metadata of a file that changes when the file is written, replaced or removed.
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// statFiles returns the current state of every file.
+func statFiles(files []string) map[string]fileState {
+	states := make(map[string]fileState, len(files))
+	for _, filename := range files {
+		if info, err := os.Stat(filename); err == nil {
+			states[filename] = fileState{exists: true, size: info.Size(), modTime: info.ModTime()}
+		} else {
+			states[filename] = fileState{}
+		}
+	}
+	return states
+}
+
+// newPollWatcher creates a watcher that compares the state of the files at every interval.
+func newPollWatcher(files []string, interval time.Duration) *watcher {
+	w := newWatcher()
+	done := make(chan struct{})
+	w.close = func() { close(done) }
+
+	// Take the first state before returning, so that changes made right after Watch returns are seen.
+	previous := statFiles(files)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// Report a change whenever the state of a file differs from the previous poll.
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				current := statFiles(files)
+				if !maps.Equal(previous, current) {
+					w.notify()
+				}
+				previous = current
+			}
+		}
+	}()
+	return w
+}
@@ -0,0 +1,89 @@
+//go:build linux
+
+package configmaster
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// inotifyEvents are the events of a directory that can change one of its files,
+// including files replaced by a rename as editors and configuration management tools do.
+const inotifyEvents = syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB | syscall.IN_CREATE |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// newNativeWatcher creates a watcher that receives inotify events for the directories of the files.
+func newNativeWatcher(files []string) (*watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("[Config-Master]: error watching files: %w", os.NewSyscallError("inotify_init1", err))
+	}
+	// The non-blocking descriptor lets the runtime poller interrupt a pending read when the file is closed.
+	inotify := os.NewFile(uintptr(fd), "inotify")
+
+	// Watch the directories, so that files that are replaced or created later are still seen.
+	directories := make(map[int32]string)
+	watched := make(map[string]bool, len(files))
+	for _, filename := range files {
+		watched[filename] = true
+		directory := filepath.Dir(filename)
+		wd, err := syscall.InotifyAddWatch(fd, directory, inotifyEvents)
+		if err != nil {
+			inotify.Close()
+			return nil, fmt.Errorf("[Config-Master]: error watching %s: %w", directory, os.NewSyscallError("inotify_add_watch", err))
+		}
+		directories[int32(wd)] = directory
+	}
+
+	w := newWatcher()
+	w.close = func() { inotify.Close() }
+	go func() {
+		buffer := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := inotify.Read(buffer)
+			if err != nil {
+				// The watcher was closed, anything else is reported.
+				if !errors.Is(err, os.ErrClosed) {
+					w.errors <- fmt.Errorf("[Config-Master]: error watching files: %w", err)
+				}
+				return
+			}
+			if changed := parseInotifyEvents(buffer[:n], directories, watched); changed {
+				w.notify()
+			}
+		}
+	}()
+	return w, nil
+}
+
+// parseInotifyEvents reports whether the events read from an inotify descriptor concern one of the watched files.
+func parseInotifyEvents(buffer []byte, directories map[int32]string, watched map[string]bool) bool {
+	changed := false
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buffer); {
+		// Every event is a fixed header followed by the NUL-padded name of the file in the watched directory.
+		wd := int32(binary.NativeEndian.Uint32(buffer[offset:]))
+		mask := binary.NativeEndian.Uint32(buffer[offset+4:])
+		length := int(binary.NativeEndian.Uint32(buffer[offset+12:]))
+		start := offset + syscall.SizeofInotifyEvent
+		if start+length > len(buffer) {
+			break
+		}
+		name := strings.TrimRight(string(buffer[start:start+length]), "\x00")
+		offset = start + length
+
+		// Events may have been lost when the queue overflowed, so assume that a file changed.
+		if mask&syscall.IN_Q_OVERFLOW != 0 {
+			changed = true
+			continue
+		}
+		if directory, exists := directories[wd]; exists && watched[filepath.Join(directory, name)] {
+			changed = true
+		}
+	}
+	return changed
+}
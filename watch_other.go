@@ -0,0 +1,10 @@
+//go:build !linux
+
+package configmaster
+
+import "errors"
+
+// newNativeWatcher reports that file system notifications are not supported, so that Watch polls the files.
+func newNativeWatcher(files []string) (*watcher, error) {
+	return nil, errors.ErrUnsupported
+}
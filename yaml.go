@@ -0,0 +1,54 @@
+package configmaster
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseYAML parses YAML configuration data into the same map shape produced by the JSON decoder.
+func parseYAML(byteValue []byte) (map[string]interface{}, error) {
+	// Unmarshal the byte slice into a map.
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(byteValue, &config); err != nil {
+		return nil, err
+	}
+
+	// Normalise the YAML specific types so that the rest of the package sees plain maps, slices and numbers.
+	for key, value := range config {
+		config[key] = normalizeValue(value)
+	}
+	return config, nil
+}
+
+// yamlLineIndex returns the line of every key of a YAML document by dotted path, or nil if it cannot be parsed.
+func yamlLineIndex(byteValue []byte) map[string]int {
+	var document yaml.Node
+	if err := yaml.Unmarshal(byteValue, &document); err != nil {
+		return nil
+	}
+	lines := make(map[string]int)
+	for _, node := range document.Content {
+		addYAMLLines("", node, lines)
+	}
+	return lines
+}
+
+// addYAMLLines records the line of every key and item below a YAML node.
+func addYAMLLines(path string, node *yaml.Node, lines map[string]int) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		// Mapping nodes hold their keys and values in pairs.
+		for index := 0; index+1 < len(node.Content); index += 2 {
+			keyPath := joinPath(path, node.Content[index].Value)
+			lines[keyPath] = node.Content[index].Line
+			addYAMLLines(keyPath, node.Content[index+1], lines)
+		}
+	case yaml.SequenceNode:
+		for index, item := range node.Content {
+			itemPath := fmt.Sprintf("%s[%d]", path, index)
+			lines[itemPath] = item.Line
+			addYAMLLines(itemPath, item, lines)
+		}
+	}
+}